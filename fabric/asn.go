@@ -0,0 +1,228 @@
+package fabric
+
+import (
+	"fmt"
+	"strconv"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+// ASNScheme selects the ASN ranges of the tiers and whether the superspine planes
+// share an ASN.
+type ASNScheme string
+
+const (
+	// ASNSchemePerPlane assigns one ASN per superspine plane, one ASN per pod
+	// for the spines and a unique ASN per leaf and borderleaf, from the 2-byte private range
+	ASNSchemePerPlane ASNScheme = "perPlane"
+	// ASNSchemeRFC7938 assigns one ASN to all superspines, one ASN per pod
+	// for the spines and a unique ASN per leaf and borderleaf, from the 4-byte private range
+	ASNSchemeRFC7938 ASNScheme = "rfc7938"
+)
+
+const (
+	// AnnotationASNScheme selects the ASNConfig Scheme in the template
+	AnnotationASNScheme = "fabric.henderiw.io/asn-scheme"
+	// AnnotationSuperspineASN selects the ASNConfig SuperspineASN in the template
+	AnnotationSuperspineASN = "fabric.henderiw.io/superspine-asn"
+	// AnnotationBorderLeafASN selects the ASNConfig BorderLeafASN in the template
+	AnnotationBorderLeafASN = "fabric.henderiw.io/borderleaf-asn"
+	// AnnotationSpineASN selects the ASNConfig SpineASN in the template
+	AnnotationSpineASN = "fabric.henderiw.io/spine-asn"
+	// AnnotationLeafASN selects the ASNConfig LeafASN in the template
+	AnnotationLeafASN = "fabric.henderiw.io/leaf-asn"
+)
+
+const (
+	maxASN2Byte = 65534
+	maxASN4Byte = 4294967294
+)
+
+var defaultASNConfigs = map[ASNScheme]*ASNConfig{
+	ASNSchemePerPlane: {
+		SuperspineASN: 64512,
+		BorderLeafASN: 64600,
+		SpineASN:      64700,
+		LeafASN:       64800,
+	},
+	ASNSchemeRFC7938: {
+		SuperspineASN: 4200000000,
+		BorderLeafASN: 4200001000,
+		SpineASN:      4200010000,
+		LeafASN:       4201000000,
+	},
+}
+
+// ASNConfig specifies how the underlay eBGP ASNs are assigned to the nodes.
+// ASNs which are not set default to the ranges of the scheme. The leaf ASNs of a pod
+// are reserved for the IPAMConfig MaxLeafsPerPod leafs.
+type ASNConfig struct {
+	Scheme ASNScheme `json:"scheme,omitempty"`
+	// SuperspineASN is the first ASN of the superspines
+	SuperspineASN uint32 `json:"superspineAsn,omitempty"`
+	// BorderLeafASN is the first ASN of the borderleafs
+	BorderLeafASN uint32 `json:"borderLeafAsn,omitempty"`
+	// SpineASN is the first ASN of the spines
+	SpineASN uint32 `json:"spineAsn,omitempty"`
+	// LeafASN is the first ASN of the leafs
+	LeafASN uint32 `json:"leafAsn,omitempty"`
+}
+
+// WithASN specifies how the underlay ASNs are assigned.
+func WithASN(c *ASNConfig) Option {
+	return func(f Fabric) {
+		f.SetASN(c)
+	}
+}
+
+func (f *fabric) SetASN(c *ASNConfig) { f.asn = c }
+
+// asnOptions returns the ASN option selected by the template annotations
+func asnOptions(annotations map[string]string) ([]Option, error) {
+	c := &ASNConfig{}
+	found := false
+	if v, ok := annotations[AnnotationASNScheme]; ok {
+		c.Scheme = ASNScheme(v)
+		found = true
+	}
+	for key, v := range map[string]*uint32{
+		AnnotationSuperspineASN: &c.SuperspineASN,
+		AnnotationBorderLeafASN: &c.BorderLeafASN,
+		AnnotationSpineASN:      &c.SpineASN,
+		AnnotationLeafASN:       &c.LeafASN,
+	} {
+		s, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		i, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s must be a number: %w", key, err)
+		}
+		*v = uint32(i)
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	return []Option{WithASN(c)}, nil
+}
+
+// allocateASNs assigns an ASN to every node and records the ASN and peer ASN on
+// every link endpoint. The ASN is derived from the indexes of the node, so re-renders
+// never reshuffle ASNs:
+// superspine -> superspineAsn + planeIndex - 1 (perPlane) or superspineAsn (rfc7938)
+// borderleaf -> borderLeafAsn + relativeNodeIndex - 1
+// spine      -> spineAsn + podIndex - 1
+// spine      -> spineAsn + (podIndex - 1) * 2 + relativeNodeIndex - 1 (peering spines, e.g. collapsed spines)
+// leaf       -> leafAsn + (podIndex - 1) * maxLeafsPerPod + relativeNodeIndex - 1
+func (f *fabric) allocateASNs() error {
+	if f.asn == nil {
+		return nil
+	}
+	scheme := f.asn.Scheme
+	if scheme == "" {
+		scheme = ASNSchemePerPlane
+	}
+	c, ok := defaultASNConfigs[scheme]
+	if !ok {
+		return fmt.Errorf("unknown asn scheme %s", scheme)
+	}
+	c = &ASNConfig{
+		Scheme:        scheme,
+		SuperspineASN: defaultUint32(f.asn.SuperspineASN, c.SuperspineASN),
+		BorderLeafASN: defaultUint32(f.asn.BorderLeafASN, c.BorderLeafASN),
+		SpineASN:      defaultUint32(f.asn.SpineASN, c.SpineASN),
+		LeafASN:       defaultUint32(f.asn.LeafASN, c.LeafASN),
+	}
+	maxLeafs := f.GetMaxLeafsPerPod()
+	maxASN := uint64(maxASN4Byte)
+	if scheme == ASNSchemePerPlane {
+		maxASN = maxASN2Byte
+	}
+
+	// spines which peer with each other need their own ASN for eBGP between them
+	spinePeering := f.wirers[TierPairSpineSpine] != nil
+
+	// owners keeps track of the group an ASN is assigned to, to detect overlapping ranges
+	owners := map[uint64]string{}
+	for _, n := range f.GetNodes() {
+		if isServer(n) {
+			continue
+		}
+		asn, owner, err := getASN(n, c, maxLeafs, spinePeering)
+		if err != nil {
+			return err
+		}
+		if asn > maxASN {
+			return fmt.Errorf("node %s: asn %d exceeds the %s asn range", n.String(), asn, scheme)
+		}
+		if o, ok := owners[asn]; ok && o != owner {
+			return fmt.Errorf("node %s: asn %d of %s overlaps with %s", n.String(), asn, owner, o)
+		}
+		owners[asn] = owner
+		n.UpdateLabel(map[string]string{KeyASN: strconv.FormatUint(asn, 10)})
+	}
+
+	for _, l := range f.GetLinks() {
+		from := l.From().(Node)
+		to := l.To().(Node)
+		if isServer(from) || isServer(to) {
+			continue
+		}
+		l.UpdateLabel(map[string]string{
+			EndpointLabelKey(from.String(), KeyASN):     from.GetASN(),
+			EndpointLabelKey(from.String(), KeyPeerASN): to.GetASN(),
+			EndpointLabelKey(to.String(), KeyASN):       to.GetASN(),
+			EndpointLabelKey(to.String(), KeyPeerASN):   from.GetASN(),
+		})
+	}
+	return nil
+}
+
+// getASN returns the ASN of the node and the group that shares the ASN, maxLeafs is the
+// number of leaf ASNs reserved per pod, spinePeering gives the spine pair of a pod an ASN per spine
+func getASN(n Node, c *ASNConfig, maxLeafs uint32, spinePeering bool) (uint64, string, error) {
+	relativeIndex, err := strconv.Atoi(n.GetRelativeNodeIndex())
+	if err != nil {
+		return 0, "", err
+	}
+	switch n.GetPosition() {
+	case string(topov1alpha1.PositionSuperspine):
+		if c.Scheme == ASNSchemeRFC7938 {
+			return uint64(c.SuperspineASN), "superspines", nil
+		}
+		planeIndex, err := strconv.Atoi(n.GetPlaneIndex())
+		if err != nil {
+			return 0, "", err
+		}
+		return uint64(c.SuperspineASN) + uint64(planeIndex-1), fmt.Sprintf("plane%d-superspines", planeIndex), nil
+	case string(topov1alpha1.PositionBorderLeaf):
+		return uint64(c.BorderLeafASN) + uint64(relativeIndex-1), n.String(), nil
+	}
+
+	podIndex, err := strconv.Atoi(n.GetPodIndex())
+	if err != nil {
+		return 0, "", err
+	}
+	if n.GetPosition() == string(topov1alpha1.PositionSpine) {
+		if spinePeering {
+			if relativeIndex > 2 {
+				return 0, "", fmt.Errorf("node %s: peering spines must be a pair", n.String())
+			}
+			return uint64(c.SpineASN) + uint64(podIndex-1)*2 + uint64(relativeIndex-1), n.String(), nil
+		}
+		return uint64(c.SpineASN) + uint64(podIndex-1), fmt.Sprintf("pod%d-spines", podIndex), nil
+	}
+	if uint32(relativeIndex) > maxLeafs {
+		return 0, "", fmt.Errorf("node %s: relative node index %d exceeds maxLeafsPerPod %d", n.String(), relativeIndex, maxLeafs)
+	}
+	return uint64(c.LeafASN) + uint64(podIndex-1)*uint64(maxLeafs) + uint64(relativeIndex-1), n.String(), nil
+}
+
+func defaultUint32(v, d uint32) uint32 {
+	if v == 0 {
+		return d
+	}
+	return v
+}
@@ -0,0 +1,168 @@
+package fabric
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCollapsedSpineASNs(t *testing.T) {
+	for _, scheme := range []ASNScheme{ASNSchemePerPlane, ASNSchemeRFC7938} {
+		t.Run(string(scheme), func(t *testing.T) {
+			f := mustNewTestFabric(t, collapsedTemplate, WithASN(&ASNConfig{Scheme: scheme}))
+			for _, l := range f.GetLinks() {
+				from, to := l.From().(Node), l.To().(Node)
+				if from.GetASN() == to.GetASN() {
+					t.Errorf("link %s connects nodes with the same asn %s", linkKey(l), from.GetASN())
+				}
+			}
+		})
+	}
+}
+
+func TestASNAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations map[string]string
+		want        *ASNConfig
+		wantErr     bool
+	}{
+		"none": {
+			annotations: map[string]string{},
+		},
+		"scheme": {
+			annotations: map[string]string{AnnotationASNScheme: "rfc7938"},
+			want:        &ASNConfig{Scheme: ASNSchemeRFC7938},
+		},
+		"bases": {
+			annotations: map[string]string{
+				AnnotationSuperspineASN: "65000",
+				AnnotationBorderLeafASN: "65010",
+				AnnotationSpineASN:      "65100",
+				AnnotationLeafASN:       "65200",
+			},
+			want: &ASNConfig{
+				SuperspineASN: 65000,
+				BorderLeafASN: 65010,
+				SpineASN:      65100,
+				LeafASN:       65200,
+			},
+		},
+		"invalid asn": {
+			annotations: map[string]string{AnnotationLeafASN: "as65200"},
+			wantErr:     true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts, err := asnOptions(tc.annotations)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("asnOptions() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			f := &fabric{}
+			for _, o := range opts {
+				o(f)
+			}
+			if !reflect.DeepEqual(f.asn, tc.want) {
+				t.Errorf("asn = %+v, want %+v", f.asn, tc.want)
+			}
+		})
+	}
+}
+
+func TestASNAnnotationsTemplate(t *testing.T) {
+	f := mustNewTestFabric(t, annotatedExampleTemplate(t,
+		AnnotationASNScheme+": rfc7938",
+		AnnotationLeafASN+`: "4210000000"`,
+	))
+	if got := getTestNode(t, f, "pod2-leaf1").GetASN(); got != "4210000032" {
+		t.Errorf("pod2-leaf1 asn = %s, want 4210000032", got)
+	}
+	if got := getTestNode(t, f, "pod2-spine1").GetASN(); got != "4200010001" {
+		t.Errorf("pod2-spine1 asn = %s, want 4200010001", got)
+	}
+}
+
+func TestASNSchemes(t *testing.T) {
+	tests := map[string]struct {
+		asn     *ASNConfig
+		ipam    *IPAMConfig
+		want    map[string]string
+		wantErr string
+	}{
+		"perPlane": {
+			asn: &ASNConfig{},
+			want: map[string]string{
+				"plane1-superspine2": "64512",
+				"plane2-superspine1": "64513",
+				"pod1-spine2":        "64700",
+				"pod2-spine1":        "64701",
+				"pod1-leaf1":         "64800",
+				"pod2-leaf3":         "64834",
+			},
+		},
+		"rfc7938": {
+			asn: &ASNConfig{Scheme: ASNSchemeRFC7938},
+			want: map[string]string{
+				"plane1-superspine2": "4200000000",
+				"plane2-superspine1": "4200000000",
+				"pod2-spine1":        "4200010001",
+				"pod2-leaf3":         "4201000034",
+			},
+		},
+		"bases": {
+			asn:  &ASNConfig{SuperspineASN: 65000, SpineASN: 65100, LeafASN: 65200},
+			ipam: &IPAMConfig{MaxLeafsPerPod: 8},
+			want: map[string]string{
+				"plane2-superspine1": "65001",
+				"pod2-spine1":        "65101",
+				"pod2-leaf3":         "65210",
+			},
+		},
+		"exceeds the 2-byte range": {
+			asn:     &ASNConfig{LeafASN: 65530},
+			wantErr: "exceeds the perPlane asn range",
+		},
+		"overlapping ranges": {
+			asn:     &ASNConfig{SpineASN: 64800},
+			wantErr: "overlaps with",
+		},
+		"leafs exceed maxLeafsPerPod": {
+			asn:     &ASNConfig{},
+			ipam:    &IPAMConfig{MaxLeafsPerPod: 2},
+			wantErr: "exceeds maxLeafsPerPod 2",
+		},
+		"unknown scheme": {
+			asn:     &ASNConfig{Scheme: "perLeaf"},
+			wantErr: "unknown asn scheme perLeaf",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := []Option{WithASN(tc.asn)}
+			if tc.ipam != nil {
+				opts = append(opts, WithIPAM(tc.ipam))
+			}
+			f, err := newTestFabric(t, exampleTemplate(t), opts...)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("New() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for node, want := range tc.want {
+				if got := getTestNode(t, f, node).GetASN(); got != want {
+					t.Errorf("%s asn = %s, want %s", node, got, want)
+				}
+			}
+			for _, l := range f.GetLinks() {
+				from, to := l.From().(Node), l.To().(Node)
+				if got := l.GetEndpointLabel(from.String(), KeyPeerASN); got != to.GetASN() {
+					t.Errorf("link %s peer asn of %s = %s, want %s", linkKey(l), from.String(), got, to.GetASN())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package fabric
+
+import (
+	"fmt"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// AnnotationBreakouts declares the broken out ports in the template, as a JSON or YAML list of PortBreakouts
+const AnnotationBreakouts = "fabric.henderiw.io/breakouts"
+
+// PortBreakout breaks out a range of ports of the nodes of a position into sub-ports.
+// The breakout must be supported by the platform in the catalog.
+type PortBreakout struct {
+	// Position is the position of the nodes, e.g. spine
+	Position string `json:"position"`
+	// Platform limits the breakout to the nodes of the platform, empty matches all platforms
+	Platform string `json:"platform,omitempty"`
+	// Ports are the ports that are broken out
+	Ports PortRange `json:"ports"`
+	// Lanes is the number of sub-ports of every port
+	Lanes uint32 `json:"lanes"`
+}
+
+// WithBreakouts breaks out ports of the nodes into sub-ports. The interface indexes
+// allocated by the Wirers fill the sub-ports of a port before the next port, e.g.
+// with ports 1-2 broken out in 4 lanes, index 1-4 are port 1 sub-port 1-4, index 5-8
+// are port 2 sub-port 1-4 and index 9 is port 3.
+func WithBreakouts(b ...*PortBreakout) Option {
+	return func(f Fabric) {
+		f.SetBreakouts(b...)
+	}
+}
+
+func (f *fabric) SetBreakouts(b ...*PortBreakout) { f.breakouts = b }
+
+// breakoutOptions returns the breakout option declared by the template annotations
+func breakoutOptions(annotations map[string]string) ([]Option, error) {
+	v, ok := annotations[AnnotationBreakouts]
+	if !ok {
+		return nil, nil
+	}
+	b := []*PortBreakout{}
+	if err := yaml.Unmarshal([]byte(v), &b); err != nil {
+		return nil, fmt.Errorf("annotation %s must be a list of breakouts: %w", AnnotationBreakouts, err)
+	}
+	return []Option{WithBreakouts(b...)}, nil
+}
+
+// getBreakouts returns the breakouts of a node of the position and platform, the breakouts
+// are validated against the breakouts of the platform in the catalog
+func (f *fabric) getBreakouts(position topov1alpha1.Position, vi *topov1alpha1.FabricTierVendorInfo, p *Platform) ([]*PortBreakout, error) {
+	breakouts := []*PortBreakout{}
+	for _, b := range f.breakouts {
+		if b.Position != string(position) || (b.Platform != "" && b.Platform != vi.Platform) {
+			continue
+		}
+		if p == nil {
+			return nil, fmt.Errorf("%s platform %s is not in the catalog and can not break out ports", position, vi.Platform)
+		}
+		if !p.SupportsBreakout(b.Ports, b.Lanes) {
+			return nil, fmt.Errorf("%s platform %s does not support breaking out ports %d-%d in %d lanes",
+				position, vi.Platform, b.Ports.Start, b.Ports.End, b.Lanes)
+		}
+		breakouts = append(breakouts, b)
+	}
+	return breakouts, nil
+}
+
+// SupportsBreakout returns true if every port in the range can be broken out in the lanes.
+func (p *Platform) SupportsBreakout(ports PortRange, lanes uint32) bool {
+	if ports.Size() == 0 || lanes < 2 {
+		return false
+	}
+	for port := ports.Start; port <= ports.End; port++ {
+		supported := false
+		for _, b := range p.Breakouts {
+			if b.Lanes == lanes && b.Ports.Contains(port) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return false
+		}
+	}
+	return true
+}
+
+// GetBreakoutSpeed returns the speed of a sub-port of the port broken out in the lanes, empty if unknown.
+func (p *Platform) GetBreakoutSpeed(port, lanes uint32) string {
+	for _, b := range p.Breakouts {
+		if b.Lanes == lanes && b.Ports.Contains(port) {
+			return b.Speed
+		}
+	}
+	return ""
+}
+
+// GetBreakoutInterfaceName returns the interface name of the sub-port of a port.
+func (p *Platform) GetBreakoutInterfaceName(port, sub uint32) string {
+	return p.getInterfaceNamer().GetInterfaceName(port, sub)
+}
+
+// portSlot is a port or a sub-port of a broken out port, sub is 0 for a port
+type portSlot struct {
+	port uint32
+	sub  uint32
+}
+
+// getPortSlots returns the ports and sub-ports of the platform in order, the interface
+// indexes of a node are the position in the slots counting from 1
+func getPortSlots(p *Platform, breakouts []*PortBreakout) []portSlot {
+	if p == nil {
+		return nil
+	}
+	slots := make([]portSlot, 0, p.Ports)
+	for port := uint32(1); port <= p.Ports; port++ {
+		lanes := uint32(0)
+		for _, b := range breakouts {
+			if b.Ports.Contains(port) {
+				lanes = b.Lanes
+			}
+		}
+		if lanes < 2 {
+			slots = append(slots, portSlot{port: port})
+			continue
+		}
+		for sub := uint32(1); sub <= lanes; sub++ {
+			slots = append(slots, portSlot{port: port, sub: sub})
+		}
+	}
+	return slots
+}
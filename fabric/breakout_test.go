@@ -0,0 +1,187 @@
+package fabric
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+func TestGetPortSlots(t *testing.T) {
+	p := &Platform{Ports: 4}
+	tests := map[string]struct {
+		breakouts []*PortBreakout
+		want      []portSlot
+	}{
+		"no breakouts": {
+			want: []portSlot{{port: 1}, {port: 2}, {port: 3}, {port: 4}},
+		},
+		"first ports": {
+			breakouts: []*PortBreakout{{Ports: PortRange{Start: 1, End: 2}, Lanes: 2}},
+			want:      []portSlot{{port: 1, sub: 1}, {port: 1, sub: 2}, {port: 2, sub: 1}, {port: 2, sub: 2}, {port: 3}, {port: 4}},
+		},
+		"last port": {
+			breakouts: []*PortBreakout{{Ports: PortRange{Start: 4, End: 4}, Lanes: 4}},
+			want:      []portSlot{{port: 1}, {port: 2}, {port: 3}, {port: 4, sub: 1}, {port: 4, sub: 2}, {port: 4, sub: 3}, {port: 4, sub: 4}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := getPortSlots(p, tc.breakouts); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("getPortSlots() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSupportsBreakout(t *testing.T) {
+	p, ok := DefaultPlatformCatalog().Get("nokiaSRL", "IXR-D2")
+	if !ok {
+		t.Fatalf("IXR-D2 not in the catalog")
+	}
+	tests := map[string]struct {
+		ports PortRange
+		lanes uint32
+		want  bool
+	}{
+		"uplinks":            {ports: PortRange{Start: 49, End: 56}, lanes: 4, want: true},
+		"single uplink":      {ports: PortRange{Start: 52, End: 52}, lanes: 4, want: true},
+		"downlinks":          {ports: PortRange{Start: 1, End: 48}, lanes: 4},
+		"range beyond ports": {ports: PortRange{Start: 49, End: 57}, lanes: 4},
+		"unsupported lanes":  {ports: PortRange{Start: 49, End: 56}, lanes: 2},
+		"single lane":        {ports: PortRange{Start: 49, End: 56}, lanes: 1},
+		"empty range":        {ports: PortRange{Start: 50, End: 49}, lanes: 4},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := p.SupportsBreakout(tc.ports, tc.lanes); got != tc.want {
+				t.Errorf("SupportsBreakout() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBreakouts(t *testing.T) {
+	spineBreakout := &PortBreakout{Position: "spine", Ports: PortRange{Start: 1, End: 2}, Lanes: 4}
+	tests := map[string]struct {
+		breakouts []*PortBreakout
+		link      string
+		node      string
+		wantName  string
+		wantSpeed string
+		wantErr   bool
+	}{
+		"no breakouts": {
+			link:      "pod1-leaf2:int-1/27--pod1-spine1:int-1/3",
+			node:      "pod1-spine1",
+			wantName:  "int-1/3",
+			wantSpeed: "100G",
+		},
+		// index 3 of the spine is the third lane of port 1
+		"spine": {
+			breakouts: []*PortBreakout{spineBreakout},
+			link:      "pod1-leaf2:int-1/27--pod1-spine1:int-1/1/3",
+			node:      "pod1-spine1",
+			wantName:  "int-1/1/3",
+			wantSpeed: "25G",
+		},
+		"spine after the broken out ports": {
+			breakouts: []*PortBreakout{spineBreakout},
+			link:      "pod1-leaf5:int-1/27--pod1-spine1:int-1/3",
+			node:      "pod1-spine1",
+			wantName:  "int-1/3",
+			wantSpeed: "100G",
+		},
+		// the leaf uplinks start at the first lane of the first uplink port
+		"leaf uplinks": {
+			breakouts: []*PortBreakout{{Position: "leaf", Platform: "IXR-D3", Ports: PortRange{Start: 27, End: 28}, Lanes: 4}},
+			link:      "pod1-leaf1:int-1/27/1--pod1-spine1:int-1/1",
+			node:      "pod1-leaf1",
+			wantName:  "int-1/27/1",
+			wantSpeed: "25G",
+		},
+		"other platform": {
+			breakouts: []*PortBreakout{{Position: "leaf", Platform: "IXR-D2", Ports: PortRange{Start: 49, End: 56}, Lanes: 4}},
+			link:      "pod1-leaf1:int-1/27--pod1-spine1:int-1/1",
+			node:      "pod1-leaf1",
+			wantName:  "int-1/27",
+			wantSpeed: "100G",
+		},
+		"unsupported": {
+			breakouts: []*PortBreakout{{Position: "spine", Ports: PortRange{Start: 1, End: 2}, Lanes: 8}},
+			wantErr:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := newTestFabric(t, fmt.Sprintf(podTemplate, 6, 1), WithBreakouts(tc.breakouts...))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			for _, l := range f.GetLinks() {
+				if linkKey(l) != tc.link {
+					continue
+				}
+				if got := l.GetLabels()[tc.node]; got != tc.wantName {
+					t.Errorf("%s interface = %s, want %s", tc.node, got, tc.wantName)
+				}
+				idx, err := strconv.ParseUint(l.GetEndpointLabel(tc.node, KeyPort), 10, 32)
+				if err != nil {
+					t.Fatalf("link %s has no %s port: %s", tc.link, tc.node, err)
+				}
+				if got := getTestNode(t, f, tc.node).GetInterfaceSpeed(uint32(idx)); got != tc.wantSpeed {
+					t.Errorf("%s speed = %s, want %s", tc.node, got, tc.wantSpeed)
+				}
+				return
+			}
+			t.Errorf("link %s not found", tc.link)
+		})
+	}
+}
+
+func TestBreakoutsNotInCatalog(t *testing.T) {
+	template := fmt.Sprintf(podTemplate, 1, 1)
+	_, err := newTestFabric(t, template,
+		WithPlatformCatalog(&PlatformCatalog{}),
+		WithBreakouts(&PortBreakout{Position: "spine", Ports: PortRange{Start: 1, End: 2}, Lanes: 4}),
+	)
+	if err == nil {
+		t.Errorf("New() error = nil, want an error for a platform which is not in the catalog")
+	}
+}
+
+func TestGetInterfaceName(t *testing.T) {
+	// the breakout of port 4 gives the node more interface indexes than platform ports
+	n, err := NewNode(&nodeInfo{
+		position:          topov1alpha1.PositionLeaf,
+		podIndex:          1,
+		relativeNodeIndex: 1,
+		platform:          &Platform{Ports: 4},
+		breakouts:         []*PortBreakout{{Ports: PortRange{Start: 4, End: 4}, Lanes: 4}},
+	})
+	if err != nil {
+		t.Fatalf("cannot create node: %s", err)
+	}
+	tests := map[string]struct {
+		idx  uint32
+		want string
+	}{
+		"zero":             {idx: 0, want: ""},
+		"first port":       {idx: 1, want: "int-1/1"},
+		"sub-port":         {idx: 5, want: "int-1/4/2"},
+		"last sub-port":    {idx: 7, want: "int-1/4/4"},
+		"beyond the ports": {idx: 8, want: "int-1/5"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := n.GetInterfaceName(tc.idx); got != tc.want {
+				t.Errorf("GetInterfaceName(%d) = %q, want %q", tc.idx, got, tc.want)
+			}
+		})
+	}
+}
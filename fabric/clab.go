@@ -0,0 +1,148 @@
+package fabric
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	targetv1 "github.com/yndd/target/apis/target/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ClabKindSRL  = "srl"
+	ClabKindSROS = "vr-sros"
+	// ClabKindLinux is used for the servers
+	ClabKindLinux = "linux"
+)
+
+var clabImages = map[string]string{
+	ClabKindSRL:   "ghcr.io/nokia/srlinux",
+	ClabKindSROS:  "vrnetlab/vr-sros:latest",
+	ClabKindLinux: "ghcr.io/hellt/network-multitool",
+}
+
+type ClabTopologyFile struct {
+	Name     string        `json:"name"`
+	Topology *ClabTopology `json:"topology"`
+}
+
+type ClabTopology struct {
+	Kinds map[string]*ClabKind `json:"kinds,omitempty"`
+	Nodes map[string]*ClabNode `json:"nodes,omitempty"`
+	Links []*ClabLink          `json:"links,omitempty"`
+}
+
+type ClabKind struct {
+	Image string `json:"image,omitempty"`
+}
+
+type ClabNode struct {
+	Kind   string            `json:"kind"`
+	Type   string            `json:"type,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type ClabLink struct {
+	Endpoints []string `json:"endpoints"`
+}
+
+// WriteContainerlab writes the fabric as a containerlab topology file with the given lab name.
+func (f *fabric) WriteContainerlab(w io.Writer, name string) error {
+	t := &ClabTopologyFile{
+		Name: name,
+		Topology: &ClabTopology{
+			Kinds: map[string]*ClabKind{},
+			Nodes: map[string]*ClabNode{},
+			Links: []*ClabLink{},
+		},
+	}
+
+	kinds := map[string]string{}
+	for _, n := range f.GetNodes() {
+		kind := ClabKindLinux
+		if !isServer(n) {
+			var err error
+			kind, err = getClabKind(n.GetVendorType())
+			if err != nil {
+				return fmt.Errorf("node %s: %w", n.String(), err)
+			}
+		}
+		kinds[n.String()] = kind
+		t.Topology.Kinds[kind] = &ClabKind{Image: clabImages[kind]}
+		t.Topology.Nodes[n.String()] = &ClabNode{
+			Kind:   kind,
+			Type:   getClabType(kind, n.GetPlatform()),
+			Labels: n.GetLabels(),
+		}
+	}
+
+	links := f.GetLinks()
+	sort.Slice(links, func(i, j int) bool { return linkKey(links[i]) < linkKey(links[j]) })
+	for _, l := range links {
+		t.Topology.Links = append(t.Topology.Links, &ClabLink{
+			Endpoints: []string{
+				fmt.Sprintf("%s:%s", l.FromNodeName(), getClabIfName(kinds[l.FromNodeName()], l, l.From().(Node))),
+				fmt.Sprintf("%s:%s", l.ToNodeName(), getClabIfName(kinds[l.ToNodeName()], l, l.To().(Node))),
+			},
+		})
+	}
+
+	b, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func getClabKind(vendorType targetv1.VendorType) (string, error) {
+	switch vendorType {
+	case targetv1.VendorTypeNokiaSRL:
+		return ClabKindSRL, nil
+	case targetv1.VendorTypeNokiaSROS:
+		return ClabKindSROS, nil
+	}
+	return "", fmt.Errorf("vendorType %s is not supported by containerlab export", vendorType)
+}
+
+// getClabType maps the platform to the containerlab node type, e.g. IXR-D3L -> ixrd3l
+func getClabType(kind, platform string) string {
+	switch kind {
+	case ClabKindSRL:
+		return strings.ToLower(strings.ReplaceAll(platform, "-", ""))
+	case ClabKindLinux:
+		// linux nodes have no type
+		return ""
+	}
+	return platform
+}
+
+// getClabIfName maps the interface name of the link endpoint of the node to the containerlab
+// naming of the kind, e.g. int-1/5 -> e1-5 and ethernet-1/5/1 -> e1-5-1 for srl. vr-sros
+// numbers the data interfaces eth1, eth2, etc. by platform port, so port 5 -> eth5. A sub-port
+// of a broken out port has no eth interface and keeps the SR OS name, e.g. 1/1/c5/2.
+func getClabIfName(kind string, l Link, n Node) string {
+	ifName := l.GetLabels()[n.String()]
+	if kind == ClabKindSROS {
+		idx, err := strconv.ParseUint(l.GetEndpointLabel(n.String(), KeyPort), 10, 32)
+		if err != nil {
+			return ifName
+		}
+		if n.GetPlatformSubPort(uint32(idx)) != 0 {
+			return ifName
+		}
+		if port := n.GetPlatformPort(uint32(idx)); port != 0 {
+			idx = uint64(port)
+		}
+		return fmt.Sprintf("eth%d", idx)
+	}
+	for _, prefix := range []string{"int-", "ethernet-"} {
+		if strings.HasPrefix(ifName, prefix) {
+			return "e" + strings.ReplaceAll(strings.TrimPrefix(ifName, prefix), "/", "-")
+		}
+	}
+	return ifName
+}
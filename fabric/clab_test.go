@@ -0,0 +1,97 @@
+package fabric
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const srosTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: sros
+spec:
+  properties:
+    fabric:
+      settings:
+        maxUplinksTier2ToTier1: 2
+        maxUplinksTier3ToTier2: 2
+      tier1:
+        num: 1
+        vendorInfo:
+        - vendorType: nokiaSROS
+          platform: SR-1
+      pod:
+      - num: 2
+        tier2:
+          num: 1
+          uplinkPerNode: 2
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: 1
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+`
+
+func TestContainerlabEndpoints(t *testing.T) {
+	tests := map[string]struct {
+		template string
+		opts     []Option
+		want     []string
+	}{
+		"no breakouts": {
+			template: srosTemplate,
+			want: []string{
+				"plane1-superspine1:eth1",
+				"plane1-superspine1:eth2",
+				"plane1-superspine1:eth3",
+				"plane1-superspine1:eth4",
+				"pod1-spine1:e1-25",
+				"pod2-spine1:e1-26",
+				"pod1-leaf1:e1-27",
+			},
+		},
+		// the sub-ports of port 2 keep the SR OS name, port 3 is the 6th interface index
+		"broken out port": {
+			template: strings.Replace(srosTemplate, "- num: 2", "- num: 3", 1),
+			opts:     []Option{WithBreakouts(&PortBreakout{Position: "superspine", Platform: "SR-1", Ports: PortRange{Start: 2, End: 2}, Lanes: 4})},
+			want: []string{
+				"plane1-superspine1:eth1",
+				"plane1-superspine1:1/1/c2/1",
+				"plane1-superspine1:1/1/c2/4",
+				"plane1-superspine1:eth3",
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := mustNewTestFabric(t, tc.template, tc.opts...)
+			var b bytes.Buffer
+			if err := f.WriteContainerlab(&b, "sros"); err != nil {
+				t.Fatalf("WriteContainerlab() error = %v", err)
+			}
+			topo := &ClabTopologyFile{}
+			if err := yaml.Unmarshal(b.Bytes(), topo); err != nil {
+				t.Fatalf("cannot read the containerlab topology: %s", err)
+			}
+			endpoints := map[string]bool{}
+			for _, l := range topo.Topology.Links {
+				for _, ep := range l.Endpoints {
+					endpoints[ep] = true
+				}
+			}
+			for _, want := range tc.want {
+				if !endpoints[want] {
+					t.Errorf("endpoint %s not found in %s", want, strings.Join(sortedKeys(endpoints), ", "))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package fabric
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+)
+
+var csvHeader = []string{"fromNode", "fromIfName", "toNode", "toIfName"}
+
+// WriteLinksCSV writes the links of the fabric as csv, one link per row.
+func (f *fabric) WriteLinksCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	links := f.GetLinks()
+	sort.Slice(links, func(i, j int) bool { return linkKey(links[i]) < linkKey(links[j]) })
+	for _, l := range links {
+		if err := cw.Write([]string{l.FromNodeName(), l.FromIfName(), l.ToNodeName(), l.ToIfName()}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,161 @@
+package fabric
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Diff is the delta between two fabrics.
+// Nodes are keyed by node name, links by the node name and interface name of both endpoints.
+type Diff struct {
+	AddedNodes   []Node
+	RemovedNodes []Node
+	ChangedNodes []NodeChange
+	AddedLinks   []Link
+	RemovedLinks []Link
+	// ChangedLinks are links where an endpoint interface is connected
+	// to a different peer, which means the interface got reindexed
+	ChangedLinks []LinkChange
+}
+
+// NodeChange is a node that exists in both fabrics with different attributes.
+type NodeChange struct {
+	Old Node
+	New Node
+}
+
+// LinkChange is a node interface that is connected to a different peer in both fabrics.
+type LinkChange struct {
+	Old Link
+	New Link
+}
+
+// IsEmpty returns true if both fabrics are the same.
+func (d *Diff) IsEmpty() bool {
+	return len(d.AddedNodes) == 0 && len(d.RemovedNodes) == 0 && len(d.ChangedNodes) == 0 &&
+		len(d.AddedLinks) == 0 && len(d.RemovedLinks) == 0 && len(d.ChangedLinks) == 0
+}
+
+// Diff returns the delta to go from fabric f to fabric other.
+func (f *fabric) Diff(other Fabric) *Diff {
+	d := &Diff{
+		AddedNodes:   []Node{},
+		RemovedNodes: []Node{},
+		ChangedNodes: []NodeChange{},
+		AddedLinks:   []Link{},
+		RemovedLinks: []Link{},
+		ChangedLinks: []LinkChange{},
+	}
+
+	oldNodes := nodesByName(f.GetNodes())
+	newNodes := nodesByName(other.GetNodes())
+
+	for _, name := range sortedKeys(oldNodes) {
+		on := oldNodes[name]
+		nn, ok := newNodes[name]
+		if !ok {
+			d.RemovedNodes = append(d.RemovedNodes, on)
+			continue
+		}
+		if !nodeEqual(on, nn) {
+			d.ChangedNodes = append(d.ChangedNodes, NodeChange{Old: on, New: nn})
+		}
+	}
+	for _, name := range sortedKeys(newNodes) {
+		if _, ok := oldNodes[name]; !ok {
+			d.AddedNodes = append(d.AddedNodes, newNodes[name])
+		}
+	}
+
+	oldLinks := linksByKey(f.GetLinks())
+	newLinks := linksByKey(other.GetLinks())
+
+	// endpoint to link lookup of the new fabric to find reindexed interfaces
+	newEndpoints := map[string]Link{}
+	for _, key := range sortedKeys(newLinks) {
+		l := newLinks[key]
+		if _, ok := oldLinks[key]; ok {
+			continue
+		}
+		newEndpoints[linkEndpoint(l.FromNodeName(), l.FromIfName())] = l
+		newEndpoints[linkEndpoint(l.ToNodeName(), l.ToIfName())] = l
+	}
+
+	changed := map[string]struct{}{}
+	for _, key := range sortedKeys(oldLinks) {
+		ol := oldLinks[key]
+		if _, ok := newLinks[key]; ok {
+			continue
+		}
+		nl, ok := newEndpoints[linkEndpoint(ol.FromNodeName(), ol.FromIfName())]
+		if !ok {
+			nl, ok = newEndpoints[linkEndpoint(ol.ToNodeName(), ol.ToIfName())]
+		}
+		if ok {
+			if _, done := changed[linkKey(nl)]; !done {
+				changed[linkKey(nl)] = struct{}{}
+				d.ChangedLinks = append(d.ChangedLinks, LinkChange{Old: ol, New: nl})
+				continue
+			}
+		}
+		d.RemovedLinks = append(d.RemovedLinks, ol)
+	}
+	for _, key := range sortedKeys(newLinks) {
+		if _, ok := oldLinks[key]; ok {
+			continue
+		}
+		if _, ok := changed[key]; ok {
+			continue
+		}
+		d.AddedLinks = append(d.AddedLinks, newLinks[key])
+	}
+
+	return d
+}
+
+func nodeEqual(a, b Node) bool {
+	return reflect.DeepEqual(a.GetLabels(), b.GetLabels()) &&
+		a.GetVendorType() == b.GetVendorType() &&
+		a.GetPlatform() == b.GetPlatform() &&
+		a.IsToBeDeployed() == b.IsToBeDeployed()
+}
+
+func nodesByName(nodes []Node) map[string]Node {
+	m := make(map[string]Node, len(nodes))
+	for _, n := range nodes {
+		m[n.String()] = n
+	}
+	return m
+}
+
+func linksByKey(links []Link) map[string]Link {
+	m := make(map[string]Link, len(links))
+	for _, l := range links {
+		m[linkKey(l)] = l
+	}
+	return m
+}
+
+// linkKey returns a key for the link that is independent of the link direction
+func linkKey(l Link) string {
+	from := linkEndpoint(l.FromNodeName(), l.FromIfName())
+	to := linkEndpoint(l.ToNodeName(), l.ToIfName())
+	if from > to {
+		from, to = to, from
+	}
+	return fmt.Sprintf("%s--%s", from, to)
+}
+
+func linkEndpoint(nodeName, ifName string) string {
+	return fmt.Sprintf("%s:%s", nodeName, ifName)
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,116 @@
+package fabric
+
+import (
+	"fmt"
+	"testing"
+)
+
+const diffTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: diff
+spec:
+  properties:
+    fabric:
+      settings:
+        maxUplinksTier3ToTier2: %d
+      pod:
+      - num: 1
+        tier2:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: %d
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: %s
+`
+
+type diffTemplateParams struct {
+	maxUplinks int
+	leafs      int
+	platform   string
+}
+
+func (p diffTemplateParams) template() string {
+	return fmt.Sprintf(diffTemplate, p.maxUplinks, p.leafs, p.platform)
+}
+
+func TestDiff(t *testing.T) {
+	base := diffTemplateParams{maxUplinks: 2, leafs: 2, platform: "IXR-D3"}
+	tests := map[string]struct {
+		new          diffTemplateParams
+		addedNodes   []string
+		removedNodes []string
+		changedNodes []string
+		addedLinks   int
+		removedLinks int
+		changedLinks int
+	}{
+		"same": {
+			new: base,
+		},
+		"leaf added": {
+			new:        diffTemplateParams{maxUplinks: 2, leafs: 3, platform: "IXR-D3"},
+			addedNodes: []string{"pod1-leaf3"},
+			addedLinks: 2,
+		},
+		"leaf removed": {
+			new:          diffTemplateParams{maxUplinks: 2, leafs: 1, platform: "IXR-D3"},
+			removedNodes: []string{"pod1-leaf2"},
+			removedLinks: 2,
+		},
+		"platform changed": {
+			new:          diffTemplateParams{maxUplinks: 2, leafs: 2, platform: "IXR-D2"},
+			changedNodes: []string{"pod1-leaf1", "pod1-leaf2"},
+			changedLinks: 4,
+		},
+		// leaf1-spine2 and leaf2-spine1 keep an endpoint, leaf2-spine2 moves both endpoints
+		"reindexed": {
+			new:          diffTemplateParams{maxUplinks: 1, leafs: 2, platform: "IXR-D3"},
+			addedLinks:   1,
+			removedLinks: 1,
+			changedLinks: 2,
+		},
+	}
+	old := mustNewTestFabric(t, base.template())
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := old.Diff(mustNewTestFabric(t, tc.new.template()))
+			if got := nodeNames(d.AddedNodes); fmt.Sprint(got) != fmt.Sprint(tc.addedNodes) {
+				t.Errorf("added nodes = %v, want %v", got, tc.addedNodes)
+			}
+			if got := nodeNames(d.RemovedNodes); fmt.Sprint(got) != fmt.Sprint(tc.removedNodes) {
+				t.Errorf("removed nodes = %v, want %v", got, tc.removedNodes)
+			}
+			changed := []Node{}
+			for _, c := range d.ChangedNodes {
+				changed = append(changed, c.New)
+			}
+			if got := nodeNames(changed); fmt.Sprint(got) != fmt.Sprint(tc.changedNodes) {
+				t.Errorf("changed nodes = %v, want %v", got, tc.changedNodes)
+			}
+			if len(d.AddedLinks) != tc.addedLinks || len(d.RemovedLinks) != tc.removedLinks || len(d.ChangedLinks) != tc.changedLinks {
+				t.Errorf("added, removed and changed links = %d, %d, %d, want %d, %d, %d",
+					len(d.AddedLinks), len(d.RemovedLinks), len(d.ChangedLinks), tc.addedLinks, tc.removedLinks, tc.changedLinks)
+			}
+			if empty := tc.new == base; d.IsEmpty() != empty {
+				t.Errorf("IsEmpty() = %t, want %t", d.IsEmpty(), empty)
+			}
+		})
+	}
+}
+
+// nodeNames returns the names of the nodes, nil if there are none
+func nodeNames(nodes []Node) []string {
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.String())
+	}
+	return names
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 
 	"github.com/yndd/ndd-runtime/pkg/logging"
 	targetv1 "github.com/yndd/target/apis/target/v1"
@@ -14,8 +14,6 @@ import (
 	"gonum.org/v1/gonum/graph/encoding/dot"
 	"gonum.org/v1/gonum/graph/multi"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -36,6 +34,23 @@ func WithClient(c client.Client) Option {
 	}
 }
 
+// WithTemplateResolver specifies how the Fabric resolves template references.
+// If no resolver is specified the templates are resolved with the client.
+func WithTemplateResolver(r TemplateResolver) Option {
+	return func(f Fabric) {
+		f.SetTemplateResolver(r)
+	}
+}
+
+// WithDefinitionResolver specifies how the Fabric resolves definition references.
+// If no resolver is specified the definitions are resolved with the template resolver,
+// if it implements DefinitionResolver, or else with the client.
+func WithDefinitionResolver(r DefinitionResolver) Option {
+	return func(f Fabric) {
+		f.SetDefinitionResolver(r)
+	}
+}
+
 // WithClient specifies the fabric to use within the client.
 func WithLocation(l *topov1alpha1.Location) Option {
 	return func(f Fabric) {
@@ -43,26 +58,74 @@ func WithLocation(l *topov1alpha1.Location) Option {
 	}
 }
 
+// WithPodIndexes specifies explicit pod indexes for the pods of the pod definition
+// at position podDefinition in the fabric template. Pods without an explicit index
+// get the lowest free pod index allocated, which changes when pods are added to an
+// earlier pod definition, so only explicit pod indexes are stable.
+func WithPodIndexes(podDefinition int, indexes ...uint32) Option {
+	return func(f Fabric) {
+		f.SetPodIndexes(podDefinition, indexes...)
+	}
+}
+
+// WithOutputDir specifies the directory GenerateJsonFile writes to.
+func WithOutputDir(dir string) Option {
+	return func(f Fabric) {
+		f.SetOutputDir(dir)
+	}
+}
+
 type Fabric interface {
 	GetNodes() []Node
 	GetLinks() []Link
 	PrintNodes()
 	PrintLinks()
-	PrintGraph()
 	GenerateJsonFile() error
+	WriteTopologyJSON(w io.Writer) error
+	WriteGraph(w io.Writer) error
+	Diff(other Fabric) *Diff
+	GetPortReport() []*NodePorts
+	GetPaths(from, to string) (*PathReport, error)
+	GetLeafPaths() []*PathReport
+	SimulateFailure(failure *Failure) (*FailureReport, error)
+	WriteContainerlab(w io.Writer, name string) error
+	WriteLinksCSV(w io.Writer) error
 
 	SetLogger(logger logging.Logger)
 	SetClient(c client.Client)
+	SetTemplateResolver(r TemplateResolver)
+	SetDefinitionResolver(r DefinitionResolver)
 	SetLocation(l *topov1alpha1.Location)
+	SetPodIndexes(podDefinition int, indexes ...uint32)
+	SetIPAM(c *IPAMConfig)
+	SetASN(c *ASNConfig)
+	SetOutputDir(dir string)
+	SetWirer(tp TierPair, w Wirer)
+	SetLeafGroups(c *LeafGroupConfig)
+	SetServers(c *ServerConfig)
+	SetPlatformCatalog(c *PlatformCatalog)
+	SetBreakouts(b ...*PortBreakout)
+	SetInterfaceNamer(vendorType targetv1.VendorType, platform string, n InterfaceNamer)
+	SetVendorAssignments(a ...*VendorAssignment)
 }
 
 func New(t *topov1alpha1.Template, opts ...Option) (Fabric, error) {
 	f := &fabric{
-		graph:     multi.NewUndirectedGraph(),
-		namespace: t.Namespace,
+		graph:           multi.NewUndirectedGraph(),
+		namespace:       t.Namespace,
+		podIndexes:      map[int][]uint32{},
+		outputDir:       DefaultOutputDir,
+		wirers:          defaultWirers(),
+		catalog:         DefaultPlatformCatalog(),
+		vendorAssigners: map[topov1alpha1.Position]*vendorAssigner{},
 	}
 
-	for _, opt := range opts {
+	// the template annotations are applied first, so the options take precedence
+	topts, err := templateOptions(t)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range append(topts, opts...) {
 		opt(f)
 	}
 
@@ -72,30 +135,40 @@ func New(t *topov1alpha1.Template, opts ...Option) (Fabric, error) {
 	if err != nil {
 		return nil, err
 	}
+	// fabrics without superspines or with a single pod don't need all settings
+	f.settings = defaultSettings(newt)
+
+	// allocate a global pod index for every pod of every pod definition
+	f.pods, err = allocatePods(newt.Pod, f.podIndexes)
+	if err != nil {
+		return nil, err
+	}
 
 	// process leaf/spine nodes
-	// p is number of pod definitions
-	for p, pod := range newt.Pod {
-		// i is the number of pods in a definition
-		for i := uint32(0); i < pod.GetPodNumber(); i++ {
-			// podIndex is pod template index * pod index within the template
-			podIndex := (uint32(p) + 1) * (i + 1)
+	for _, pod := range f.pods {
+		// tier 2 -> spines in the pod
+		if err := f.processTier(topov1alpha1.PositionSpine, pod.index, pod.template.Tier2, pod.template.IsToBeDeployed()); err != nil {
+			return nil, err
+		}
+		// tier 3 -> leafs in the pod
+		if err := f.processTier(topov1alpha1.PositionLeaf, pod.index, pod.template.Tier3, pod.template.IsToBeDeployed()); err != nil {
+			return nil, err
+		}
+	}
 
-			//log.Debug("podIndex", "podIndex", podIndex)
+	// group the leafs in redundancy groups
+	if err := f.groupLeafs(); err != nil {
+		return nil, err
+	}
 
-			// tier 2 -> spines in the pod
-			if err := f.processTier(topov1alpha1.PositionSpine, podIndex, pod.Tier2, pod.IsToBeDeployed()); err != nil {
-				return nil, err
-			}
-			// tier 3 -> leafs in the pod
-			if err := f.processTier(topov1alpha1.PositionLeaf, podIndex, pod.Tier3, pod.IsToBeDeployed()); err != nil {
-				return nil, err
-			}
-		}
+	// process the servers in the racks below the leafs
+	if err := f.processServers(); err != nil {
+		return nil, err
 	}
 
 	// proces superspines
 	// the superspine is equal to the amount of spines per pod and multiplied with the number in the template
+	// every spine index has its own plane and every plane has tier1 num superspines
 	if newt.Tier1 != nil {
 		// process superspine nodes
 		for n := uint32(0); n < newt.GetSuperSpines(); n++ {
@@ -114,206 +187,79 @@ func New(t *topov1alpha1.Template, opts ...Option) (Fabric, error) {
 
 	}
 
-	// wire things
-
-	// process spine-leaf links
-	for p, pod := range newt.Pod {
-		// i is the number of pods in a definition
-		for i := 0; i < int(pod.GetPodNumber()); i++ {
-			// podIndex is pod template index * pod index within the template
-			podIndex := (p + 1) * (i + 1)
-
-			// identify all the leafs and spines in the podIndex
-			// from -> tier2 or spines
-			// to -> tier 3 or leafs
-			tier2Selector := labels.NewSelector()
-			tier3Selector := labels.NewSelector()
-
-			tier2Req, _ := labels.NewRequirement(KeyPosition, selection.Equals, []string{string(topov1alpha1.PositionSpine)})
-			tier3Req, _ := labels.NewRequirement(KeyPosition, selection.Equals, []string{string(topov1alpha1.PositionLeaf)})
-
-			// select the POD Index
-			podIdxReq, _ := labels.NewRequirement(KeyPodIndex, selection.Equals, []string{strconv.Itoa(podIndex)})
-
-			tier2Selector = tier2Selector.Add(*tier2Req, *podIdxReq)
-			tier3Selector = tier3Selector.Add(*tier3Req, *podIdxReq)
-
-			tier2Nodes := f.nodesByLabel(tier2Selector)
-			tier3Nodes := f.nodesByLabel(tier3Selector)
-
-			for _, tier2Node := range tier2Nodes {
-				//tier2NodeIndex := uint32(n) + 1
-				for _, tier3Node := range tier3Nodes {
-					//tier3NodeIndex := uint32(m) + 1
-					// validate if the uplinks per node is not greater than max uplinks
-					// otherwise there is a conflict and the algorithm behind will create
-					// overlapping indexes
-					uplinksPerNode := tier3Node.GetUplinkPerNode()
-					if uplinksPerNode > newt.Settings.MaxUplinksTier3ToTier2 {
-						return nil, fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier3ToTier2 %d",
-							uplinksPerNode, newt.Settings.MaxUplinksTier3ToTier2)
-					}
-
-					// the algorithm needs to avoid reindixing if changes happen -> introduced maxNumUplinks
-					// the allocation is first allocating the uplink Index
-					// u represnts the actual uplink index
-					// spine Index    -> actualUplinkId + (actual leafs  * max uplinks)
-					// leaf  Index    -> actualUplinkId + (actual spines * max uplinks)
-					// actualUplinkId = u + 1 -> counting starts at 1
-					// actual leafs   = tier3NodeIndex - 1 -> counting from 0
-					// actual spines  = tier2NodeIndex - 1 -> counting from 0
-					// max uplinks    = mergedTemplate.MaxUplinksTier3ToTier2
-					for u := uint32(0); u < uplinksPerNode; u++ {
-
-						l := f.addLink(tier2Node, tier3Node)
-
-						tier3NodeIndex, err := strconv.Atoi(tier3Node.GetRelativeNodeIndex())
-						if err != nil {
-							return nil, err
-						}
-						tier2NodeIndex, err := strconv.Atoi(tier2Node.GetRelativeNodeIndex())
-						if err != nil {
-							return nil, err
-						}
-
-						label := map[string]string{
-							tier2Node.String(): tier2Node.GetInterfaceName(u + 1 + ((uint32(tier3NodeIndex) - 1) * newt.Settings.MaxUplinksTier3ToTier2)),
-							tier3Node.String(): tier3Node.GetInterfaceNameWithPlatfromOffset(u + 1 + ((uint32(tier2NodeIndex) - 1) * newt.Settings.MaxUplinksTier3ToTier2)),
-						}
-						l.SetLabel(label)
-
-						f.graph.SetLine(l)
-
-						f.log.Debug("Adding link", "from:", tier2Node.String(), "itfce", label[tier2Node.String()], "to:", tier3Node.String(), "itfce", label[tier3Node.String()])
-					}
-				}
-			}
-		}
+	if err := f.validateVendorAssignments(); err != nil {
+		return nil, err
+	}
 
-		tier1Selector := labels.NewSelector()
-		tier1Req, _ := labels.NewRequirement(KeyPosition, selection.Equals, []string{string(topov1alpha1.PositionSuperspine)})
-		tier1Selector = tier1Selector.Add(*tier1Req)
-		tier1Nodes := f.nodesByLabel(tier1Selector)
-
-		tier2Selector := labels.NewSelector()
-		tier2Req, _ := labels.NewRequirement(KeyPosition, selection.Equals, []string{string(topov1alpha1.PositionSpine)})
-		tier2Selector = tier2Selector.Add(*tier2Req)
-		tier2Nodes := f.nodesByLabel(tier2Selector)
-
-		// process superspine-spine links
-		for _, tier1Node := range tier1Nodes {
-			for _, tier2Node := range tier2Nodes {
-				// validate if the uplinks per node is not greater than max uplinks
-				// otherwise there is a conflict and the algorithm behind will create
-				// overlapping indexes
-				uplinksPerNode := tier2Node.GetUplinkPerNode()
-				if uplinksPerNode > newt.Settings.MaxUplinksTier2ToTier1 {
-					return nil, fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, newt.Settings.MaxUplinksTier2ToTier1)
-				}
+	if err := f.build(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
 
-				// spine and superspine line up so we only create a link if there is a match
-				// on the indexes
-				if tier2Node.GetRelativeNodeIndex() == tier1Node.GetPlaneIndex() {
-					// the algorithm needs to avoid reindixing if changes happen -> introduced maxNumUplinks
-					// the allocation is first allocating the uplink Index
-					// u represnts the actual uplink index
-					// superspine Index -> actualUplinkId + (actual podIndex  * max uplinks)
-					// spine Index      -> actualUplinkId + (actual spines per plane * max uplinks)
-					// actualUplinkId          = u + 1 -> counting starts at 1
-					// actual PodIndex         = p +1
-					// actual spines per plane = tier1Node.GetNodePlaneIndex() - 1
-					// max uplinks             = mergedTemplate.MaxUplinksTier2ToTier1
-					for u := uint32(0); u < uplinksPerNode; u++ {
-
-						l := f.addLink(tier1Node, tier2Node)
-
-						podIndex, err := strconv.Atoi(tier2Node.GetPodIndex())
-						if err != nil {
-							return nil, err
-						}
-						relativeIndex, err := strconv.Atoi(tier1Node.GetRelativeNodeIndex())
-						if err != nil {
-							return nil, err
-						}
-
-						label := map[string]string{
-							tier1Node.String(): tier1Node.GetInterfaceName(u + 1 + (uint32(podIndex-1) * newt.Settings.MaxUplinksTier2ToTier1)),
-							tier2Node.String(): tier2Node.GetInterfaceNameWithPlatfromOffset(u + 1 + (uint32(relativeIndex-1) * newt.Settings.MaxUplinksTier2ToTier1)),
-						}
-						l.SetLabel(label)
-
-						f.graph.SetLine(l)
-
-						f.log.Debug("Adding link", "from:", tier1Node.String(), "itfce", label[tier1Node.String()], "to:", tier2Node.String(), "itfce", label[tier2Node.String()])
-					}
-				}
-			}
-		}
+// build wires the nodes and allocates the addresses and ASNs
+func (f *fabric) build() error {
+	// wire things
+	if err := f.wire(); err != nil {
+		return err
+	}
 
-		blSelector := labels.NewSelector()
-		blReq, _ := labels.NewRequirement(KeyPosition, selection.Equals, []string{string(topov1alpha1.PositionBorderLeaf)})
-		blSelector = blSelector.Add(*blReq)
-		blNodes := f.nodesByLabel(blSelector)
-
-		// process borderleaf-spine links
-		for _, blNode := range blNodes {
-			for _, tier2Node := range tier2Nodes {
-				// validate if the uplinks per node is not greater than max uplinks
-				// otherwise there is a conflict and the algorithm behind will create
-				// overlapping indexes
-				uplinksPerNode := tier2Node.GetUplinkPerNode()
-				if uplinksPerNode > newt.Settings.MaxUplinksTier2ToTier1 {
-					return nil, fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, newt.Settings.MaxUplinksTier2ToTier1)
-				}
+	// validate the allocated ports fit on the platforms
+	if err := f.validatePorts(); err != nil {
+		return err
+	}
 
-				for u := uint32(0); u < uplinksPerNode; u++ {
-
-					l := f.addLink(blNode, tier2Node)
-
-					podIndex, err := strconv.Atoi(tier2Node.GetPodIndex())
-					if err != nil {
-						return nil, err
-					}
-					if uint32(podIndex) > newt.Settings.MaxUplinksTier2ToTier1 {
-						return nil, fmt.Errorf("spines per pod cannot be bigger than maxSpinesPerPod")
-					}
-					tier2NodeIndex, err := strconv.Atoi(tier2Node.GetRelativeNodeIndex())
-					if err != nil {
-						return nil, err
-					}
-					blNodeIndex, err := strconv.Atoi(blNode.GetRelativeNodeIndex())
-					if err != nil {
-						return nil, err
-					}
-
-					label := map[string]string{
-						blNode.String():    blNode.GetInterfaceName(u + 1 + ((uint32(podIndex-1) + ((uint32(tier2NodeIndex) - 1) * newt.Settings.MaxSpinesPerPod)) * newt.Settings.MaxUplinksTier2ToTier1)),
-						tier2Node.String(): tier2Node.GetInterfaceNameWithPlatfromOffset(u + 1 + (uint32(blNodeIndex-1) * newt.Settings.MaxUplinksTier2ToTier1)),
-					}
-					l.SetLabel(label)
-
-					f.graph.SetLine(l)
-
-					f.log.Debug("Adding link", "from:", blNode.String(), "itfce", label[blNode.String()], "to:", tier2Node.String(), "itfce", label[tier2Node.String()])
-				}
-			}
-		}
+	// allocate the system and link addresses
+	if err := f.allocateSystemAddresses(); err != nil {
+		return err
+	}
+	if err := f.allocateLinkAddresses(); err != nil {
+		return err
 	}
 
-	return f, nil
+	// assign the underlay ASNs
+	return f.allocateASNs()
 }
 
 type fabric struct {
-	log       logging.Logger
-	client    client.Client
-	graph     *multi.UndirectedGraph
-	location  *topov1alpha1.Location
-	namespace string
+	log      logging.Logger
+	client   client.Client
+	resolver TemplateResolver
+	// definitionResolver resolves the definitions of already deployed pods
+	definitionResolver DefinitionResolver
+	graph              *multi.UndirectedGraph
+	location           *topov1alpha1.Location
+	namespace          string
+	settings           *topov1alpha1.FabricTemplateSettings
+	// podIndexes are the explicit pod indexes per pod definition
+	podIndexes map[int][]uint32
+	pods       []*pod
+	wirers     map[TierPair]Wirer
+	ipam       *IPAMConfig
+	asn        *ASNConfig
+	leafGroups *LeafGroupConfig
+	servers    *ServerConfig
+	catalog    *PlatformCatalog
+	breakouts  []*PortBreakout
+	namers     map[namerKey]InterfaceNamer
+	// vendorAssignments are the vendor assignments per position
+	vendorAssignments []*VendorAssignment
+	// vendorAssigners are the vendor assigners per position, shared by all tiers of the position
+	vendorAssigners map[topov1alpha1.Position]*vendorAssigner
+	outputDir       string
 }
 
-func (f *fabric) SetLogger(log logging.Logger)         { f.log = log }
-func (f *fabric) SetClient(c client.Client)            { f.client = c }
+func (f *fabric) SetLogger(log logging.Logger)           { f.log = log }
+func (f *fabric) SetClient(c client.Client)              { f.client = c }
+func (f *fabric) SetTemplateResolver(r TemplateResolver) { f.resolver = r }
+func (f *fabric) SetDefinitionResolver(r DefinitionResolver) {
+	f.definitionResolver = r
+}
 func (f *fabric) SetLocation(l *topov1alpha1.Location) { f.location = l }
+func (f *fabric) SetOutputDir(dir string)              { f.outputDir = dir }
+func (f *fabric) SetPodIndexes(podDefinition int, indexes ...uint32) {
+	f.podIndexes[podDefinition] = indexes
+}
 
 func (f *fabric) GetNodes() []Node {
 	nodes := make([]Node, 0)
@@ -372,6 +318,8 @@ func (f *fabric) printNode(n Node) {
 			"nodeName", n.String(),
 			"podIndex", n.GetPodIndex(),
 			"relativeNodeIndex", n.GetRelativeNodeIndex(),
+			"redundancyGroup", n.GetRedundancyGroup(),
+			"toBeDeployed", n.IsToBeDeployed(),
 			//"vendorType", n.GetVendorType(),
 			//"platform", n.GetPlatform(),
 			"location", n.GetLocation(),
@@ -393,6 +341,7 @@ func (f *fabric) printLink(l Link) {
 		f.log.Debug("link",
 			"from nodeName", from.String(),
 			"from planeIndex", from.GetPlaneIndex(),
+			"from relativeNodeIndex", from.GetRelativeNodeIndex(),
 			"from ifName", l.GetLabels()[from.String()],
 			"to nodeName", to.String(),
 			"to podIndex", to.GetPodIndex(),
@@ -412,20 +361,36 @@ func (f *fabric) printLink(l Link) {
 }
 
 func (f *fabric) processTier(position topov1alpha1.Position, index uint32, tierTempl *topov1alpha1.TierTemplate, toBeDeployed bool) error {
+	// a pod can have no leafs, e.g. a collapsed spine pair
+	if tierTempl == nil || tierTempl.NodeNumber == 0 {
+		return nil
+	}
 	vendorNum := len(tierTempl.VendorInfo)
+	if vendorNum == 0 {
+		return fmt.Errorf("%s tier has no vendorInfo", position)
+	}
+	platforms := make([]*Platform, vendorNum)
+	namers := make([]InterfaceNamer, vendorNum)
+	breakouts := make([][]*PortBreakout, vendorNum)
+	for i, vi := range tierTempl.VendorInfo {
+		platforms[i] = f.getPlatform(vi)
+		namers[i] = f.getInterfaceNamer(vi, platforms[i])
+		b, err := f.getBreakouts(position, vi, platforms[i])
+		if err != nil {
+			return err
+		}
+		breakouts[i] = b
+	}
+	va, err := f.getVendorAssigner(position, uint32(vendorNum))
+	if err != nil {
+		return err
+	}
 	for n := uint32(0); n < tierTempl.NodeNumber; n++ {
-		// venndor Index is used to map to the particular node based on modulo
-		// if 1 vendor  -> all nodes are from 1 vendor
-		// if 2 vendors -> all odd nodes will be vendor A and all even nodes will be vendor B
-		// if 3 vendors -> 1st is vendorA, 2nd vendor B, 3rd is vendor C
-		vendorIdx := n % uint32(vendorNum)
-
 		ni := &nodeInfo{
 			position:          position,
 			graphIndex:        f.graph.NewNode().ID(),
 			relativeNodeIndex: n + 1,
 			uplinkPerNode:     tierTempl.UplinksPerNode,
-			vendorInfo:        tierTempl.VendorInfo[vendorIdx],
 			toBeDeployed:      toBeDeployed,
 			location:          f.location,
 		}
@@ -441,6 +406,16 @@ func (f *fabric) processTier(position topov1alpha1.Position, index uint32, tierT
 			ni.podIndex = index
 		}
 
+		// the vendor assignment maps the node to a vendor info, by default based on modulo
+		// if 1 vendor  -> all nodes are from 1 vendor
+		// if 2 vendors -> all odd nodes will be vendor A and all even nodes will be vendor B
+		// if 3 vendors -> 1st is vendorA, 2nd vendor B, 3rd is vendor C
+		vendorIdx := va.next(ni, uint32(vendorNum))
+		ni.vendorInfo = tierTempl.VendorInfo[vendorIdx]
+		ni.platform = platforms[vendorIdx]
+		ni.namer = namers[vendorIdx]
+		ni.breakouts = breakouts[vendorIdx]
+
 		n, err := NewNode(ni)
 		if err != nil {
 			return err
@@ -450,6 +425,16 @@ func (f *fabric) processTier(position topov1alpha1.Position, index uint32, tierT
 	return nil
 }
 
+// getPlatform returns the platform of the vendor info from the catalog, nil if it is not in the catalog
+func (f *fabric) getPlatform(vi *topov1alpha1.FabricTierVendorInfo) *Platform {
+	p, ok := f.catalog.Get(vi.VendorType, vi.Platform)
+	if !ok {
+		f.log.Debug("platform not in catalog", "vendorType", vi.VendorType, "platform", vi.Platform)
+		return nil
+	}
+	return p
+}
+
 func (f *fabric) addNode(n Node) {
 	f.graph.AddNode(n)
 }
@@ -504,36 +489,26 @@ func (f *fabric) parseTemplate(t *topov1alpha1.FabricTemplate) (*topov1alpha1.Fa
 
 		mt.Pod = make([]*topov1alpha1.PodTemplate, 0)
 		for _, pod := range t.Pod {
-			if pod.TemplateRef != nil {
-				pd, err := f.getPodDefintionFromTemplate(pod.TemplateRef.Name)
+			switch {
+			case pod.TemplateRef != nil:
+				pd, err := f.getPodDefintionFromTemplate(f.namespace, pod.TemplateRef.Name)
 				if err != nil {
 					return nil, err
 				}
 				pd.SetToBeDeployed(true)
 				mt.Pod = append(mt.Pod, pd)
-			}
-			/*
-				if pod.DefinitionReference != nil {
-					name, namespace := meta.NamespacedName(*pod.DefinitionReference).GetNameAndNamespace()
-					t := &topov1alpha1.Definition{}
-					if err := f.client.Get(context.TODO(), types.NamespacedName{
-						Namespace: namespace,
-						Name:      name,
-					}, t); err != nil {
-						return nil, err
-					}
-					if len(t.Spec.Properties.Templates) != 1 {
-						return nil, fmt.Errorf("definition can only have 1 template")
-					}
-
-					pd, err := f.getPodDefintionFromTemplate(t.Spec.Properties.Templates[0].NamespacedName)
-					if err != nil {
-						return nil, err
-					}
-					pd.SetToBeDeployed(false)
-					mt.Pod = append(mt.Pod, pd)
+			case pod.DefinitionReference != nil:
+				// pods of a definition are already deployed
+				pd, err := f.getPodDefintionFromDefinition(*pod.DefinitionReference)
+				if err != nil {
+					return nil, err
 				}
-			*/
+				pd.SetToBeDeployed(false)
+				mt.Pod = append(mt.Pod, pd)
+			default:
+				pod.SetToBeDeployed(true)
+				mt.Pod = append(mt.Pod, pod)
+			}
 		}
 	} else {
 		mt = t
@@ -545,25 +520,137 @@ func (f *fabric) parseTemplate(t *topov1alpha1.FabricTemplate) (*topov1alpha1.Fa
 	return mt, nil
 }
 
-func (f *fabric) getPodDefintionFromTemplate(name string) (*topov1alpha1.PodTemplate, error) {
-	t := &topov1alpha1.Template{}
-	if err := f.client.Get(context.TODO(), types.NamespacedName{
-		Namespace: f.namespace,
-		Name:      name,
-	}, t); err != nil {
+// defaultSettings returns a copy of the template settings, the settings that are not set
+// are derived from the pods, so fabrics without superspines or settings can be build.
+func defaultSettings(t *topov1alpha1.FabricTemplate) *topov1alpha1.FabricTemplateSettings {
+	s := &topov1alpha1.FabricTemplateSettings{}
+	if t.Settings != nil {
+		*s = *t.Settings
+	}
+
+	var maxSpines, maxTier2Uplinks, maxTier3Uplinks uint32
+	for _, pod := range t.Pod {
+		if pod.Tier2 != nil {
+			maxSpines = maxUint32(maxSpines, pod.Tier2.NodeNumber)
+			maxTier2Uplinks = maxUint32(maxTier2Uplinks, pod.Tier2.UplinksPerNode)
+		}
+		if pod.Tier3 != nil {
+			maxTier3Uplinks = maxUint32(maxTier3Uplinks, pod.Tier3.UplinksPerNode)
+		}
+	}
+	if t.BorderLeaf != nil {
+		maxTier2Uplinks = maxUint32(maxTier2Uplinks, t.BorderLeaf.UplinksPerNode)
+	}
+
+	if s.MaxSpinesPerPod == 0 {
+		s.MaxSpinesPerPod = maxUint32(maxSpines, 1)
+	}
+	if s.MaxUplinksTier2ToTier1 == 0 {
+		s.MaxUplinksTier2ToTier1 = maxUint32(maxTier2Uplinks, 1)
+	}
+	if s.MaxUplinksTier3ToTier2 == 0 {
+		s.MaxUplinksTier3ToTier2 = maxUint32(maxTier3Uplinks, 1)
+	}
+	return s
+}
+
+func maxUint32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// getPodDefintionFromTemplate returns a copy of the pod definition of the referenced template,
+// so the same template can be referenced multiple times
+func (f *fabric) getPodDefintionFromTemplate(namespace, name string) (*topov1alpha1.PodTemplate, error) {
+	r, err := f.getTemplateResolver()
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve template reference %s: %w", name, err)
+	}
+	if namespace == "" {
+		namespace = f.namespace
+	}
+	t, err := r.GetTemplate(context.TODO(), namespace, name)
+	if err != nil {
 		return nil, err
 	}
+	if t.Spec.Properties == nil || t.Spec.Properties.Fabric == nil {
+		return nil, fmt.Errorf("template %s has no fabric properties", name)
+	}
 	if err := t.Spec.Properties.Fabric.CheckTemplate(false); err != nil {
 		return nil, err
 	}
-	return t.Spec.Properties.Fabric.Pod[0], nil
+	pd := *t.Spec.Properties.Fabric.Pod[0]
+	return &pd, nil
 }
 
-func (f *fabric) PrintGraph() {
-	result, _ := dot.Marshal(f.graph, "", "", "  ")
-	fmt.Print(string(result))
+// getPodDefintionFromDefinition returns the pod definition of the template of the
+// referenced definition, the reference is formatted as namespace/name or name
+func (f *fabric) getPodDefintionFromDefinition(ref string) (*topov1alpha1.PodTemplate, error) {
+	r, err := f.getDefinitionResolver()
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve definition reference %s: %w", ref, err)
+	}
+	namespace, name := splitNamespacedName(ref)
+	if namespace == "" {
+		namespace = f.namespace
+	}
+	d, err := r.GetDefinition(context.TODO(), namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if d.Spec.Properties == nil || len(d.Spec.Properties.Templates) != 1 {
+		return nil, fmt.Errorf("definition %s can only have 1 template", ref)
+	}
+
+	templateNamespace, templateName := splitNamespacedName(d.Spec.Properties.Templates[0].NamespacedName)
+	if templateNamespace == "" {
+		templateNamespace = namespace
+	}
+	return f.getPodDefintionFromTemplate(templateNamespace, templateName)
+}
+
+func (f *fabric) getTemplateResolver() (TemplateResolver, error) {
+	if f.resolver != nil {
+		return f.resolver, nil
+	}
+	if f.client != nil {
+		return NewClientResolver(f.client), nil
+	}
+	return nil, fmt.Errorf("no template resolver or client")
+}
+
+func (f *fabric) getDefinitionResolver() (DefinitionResolver, error) {
+	if f.definitionResolver != nil {
+		return f.definitionResolver, nil
+	}
+	if r, ok := f.resolver.(DefinitionResolver); ok {
+		return r, nil
+	}
+	if f.client != nil {
+		return NewClientResolver(f.client), nil
+	}
+	return nil, fmt.Errorf("no definition resolver or client")
+}
+
+// WriteGraph writes the fabric graph in DOT format.
+func (f *fabric) WriteGraph(w io.Writer) error {
+	result, err := dot.Marshal(f.graph, "", "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(result)
+	return err
 }
 
+const (
+	// DefaultOutputDir is the default directory GenerateJsonFile writes to
+	DefaultOutputDir = "out"
+	// TopologyJsonFileName is the file GenerateJsonFile writes to
+	TopologyJsonFileName = "fabric.json"
+)
+
 type TopologyJsonNode struct {
 	ID    int                   `json:"id"`
 	Label string                `json:"label"`
@@ -577,6 +664,10 @@ type TopologyJsonNodedata struct {
 	ExpectedSWVersion string `json:"expectedSWVersion,omitempty"`
 	MgmtIP            string `json:"mgmtIp,omitempty"`
 	Model             string `json:"model,omitempty"`
+	SystemIPv4        string `json:"systemIpv4,omitempty"`
+	SystemIPv6        string `json:"systemIpv6,omitempty"`
+	RouterID          string `json:"routerId,omitempty"`
+	ASN               string `json:"asn,omitempty"`
 }
 
 type TopologyJsonLink struct {
@@ -589,13 +680,28 @@ type TopologyJsonFile struct {
 	Edges []*TopologyJsonLink `json:"edges,omitempty"`
 }
 
-func (f *fabric) GenerateJsonFile() error {
+// WriteTopologyJSON writes the nodes and links of the fabric as topology json.
+func (f *fabric) WriteTopologyJSON(w io.Writer) error {
 	t := &TopologyJsonFile{
 		Nodes: []*TopologyJsonNode{},
 		Edges: []*TopologyJsonLink{},
 	}
 
 	nodes := f.GetNodes()
+
+	// the levels start at the highest tier of the fabric, e.g. the spines
+	// are the top level of a fabric without superspines
+	topLevel := -1
+	for _, n := range nodes {
+		level := getLevel(n)
+		if level >= 0 && (topLevel < 0 || level < topLevel) {
+			topLevel = level
+		}
+	}
+	if topLevel < 0 {
+		topLevel = 0
+	}
+
 	for _, n := range nodes {
 
 		vendorType := ""
@@ -610,12 +716,16 @@ func (f *fabric) GenerateJsonFile() error {
 
 		t.Nodes = append(t.Nodes, &TopologyJsonNode{
 			ID:    int(n.ID()),
-			Level: topov1alpha1.GetLevel(topov1alpha1.Position(n.GetPosition())),
+			Level: getLevel(n) - topLevel,
 			Label: n.String(),
 			Nos:   vendorType,
 			Cid:   n.GetPosition(),
 			Data: &TopologyJsonNodedata{
-				Model: n.GetPlatform(),
+				Model:      n.GetPlatform(),
+				SystemIPv4: n.GetSystemIPv4(),
+				SystemIPv6: n.GetSystemIPv6(),
+				RouterID:   n.GetRouterID(),
+				ASN:        n.GetASN(),
 			},
 		})
 	}
@@ -632,20 +742,35 @@ func (f *fabric) GenerateJsonFile() error {
 	if err != nil {
 		return err
 	}
+	if _, err := w.Write(j); err != nil {
+		return err
+	}
+	return nil
+}
 
-	fmt.Printf("json output: \n%s\n", j)
-
-	filepath := filepath.Join("out", "fabric.json")
+// getLevel returns the level of the node in the topology json, the servers are below the leafs
+func getLevel(n Node) int {
+	if isServer(n) {
+		return topov1alpha1.GetLevel(topov1alpha1.PositionLeaf) + 1
+	}
+	return topov1alpha1.GetLevel(topov1alpha1.Position(n.GetPosition()))
+}
 
-	file, err := os.Create(filepath)
-	if err != nil {
+// GenerateJsonFile writes the topology json to fabric.json in the output directory.
+func (f *fabric) GenerateJsonFile() (err error) {
+	if err := os.MkdirAll(f.outputDir, 0755); err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(filepath, j, 0644); err != nil {
+	file, err := os.Create(filepath.Join(f.outputDir, TopologyJsonFileName))
+	if err != nil {
 		return err
 	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
 
-	defer file.Close()
-	return nil
+	return f.WriteTopologyJSON(file)
 }
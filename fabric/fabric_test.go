@@ -0,0 +1,85 @@
+package fabric
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yndd/ndd-runtime/pkg/logging"
+)
+
+// newTestFabric builds the fabric of the template in YAML
+func newTestFabric(t *testing.T, template string, opts ...Option) (Fabric, error) {
+	t.Helper()
+	tmpl, err := ReadTemplate(strings.NewReader(template), "")
+	if err != nil {
+		t.Fatalf("cannot read template: %s", err)
+	}
+	return New(tmpl, append([]Option{WithLogger(logging.NewNopLogger())}, opts...)...)
+}
+
+// mustNewTestFabric builds the fabric of the template in YAML and fails the test on an error
+func mustNewTestFabric(t *testing.T, template string, opts ...Option) Fabric {
+	t.Helper()
+	f, err := newTestFabric(t, template, opts...)
+	if err != nil {
+		t.Fatalf("cannot build fabric: %s", err)
+	}
+	return f
+}
+
+// exampleTemplate returns the template of the example directory
+func exampleTemplate(t *testing.T) string {
+	t.Helper()
+	b, err := os.ReadFile("../example/template.yaml")
+	if err != nil {
+		t.Fatalf("cannot read example template: %s", err)
+	}
+	return string(b)
+}
+
+// annotatedExampleTemplate returns the example template with the annotations, one
+// "key: value" line each
+func annotatedExampleTemplate(t *testing.T, annotations ...string) string {
+	t.Helper()
+	return strings.Replace(exampleTemplate(t), "  namespace: default\n",
+		"  namespace: default\n  annotations:\n    "+strings.Join(annotations, "\n    ")+"\n", 1)
+}
+
+// getTestNode returns the node with the name and fails the test if it does not exist
+func getTestNode(t *testing.T, f Fabric, name string) Node {
+	t.Helper()
+	for _, n := range f.GetNodes() {
+		if n.String() == name {
+			return n
+		}
+	}
+	t.Fatalf("node %s not found", name)
+	return nil
+}
+
+const collapsedTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: collapsed
+  annotations:
+    fabric.henderiw.io/collapsed-spines: "true"
+spec:
+  properties:
+    fabric:
+      pod:
+      - num: 2
+        tier2:
+          num: 2
+          uplinkPerNode: 2
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: 4
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D2
+`
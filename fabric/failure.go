@@ -0,0 +1,269 @@
+package fabric
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Failure is a set of nodes and links which fail, by name or label selector, e.g. the
+// node selector position=superspine,planeIndex=1 fails superspine plane 1.
+type Failure struct {
+	// Nodes are the names of the failed nodes
+	Nodes []string `json:"nodes,omitempty"`
+	// NodeSelector is a label selector of the failed nodes
+	NodeSelector string `json:"nodeSelector,omitempty"`
+	// Links are the names of the failed links, e.g. pod1-leaf1:int-1/49--pod1-spine1:int-1/1
+	Links []string `json:"links,omitempty"`
+	// LinkSelector is a label selector of the failed links
+	LinkSelector string `json:"linkSelector,omitempty"`
+}
+
+// FailureReport is the impact of a failure on the leafs of the fabric.
+type FailureReport struct {
+	// FailedNodes are the failed nodes, ordered by name
+	FailedNodes []string `json:"failedNodes"`
+	// FailedLinks are the failed links, including the links of the failed nodes, ordered by name
+	FailedLinks []string `json:"failedLinks"`
+	// LeafPairs are the paths between every pair of leafs which did not fail, ordered by node names
+	LeafPairs []*LeafPairImpact `json:"leafPairs"`
+	// LostPaths is the number of equal-cost paths lost over all leaf pairs
+	LostPaths int `json:"lostPaths"`
+	// Disconnected is the number of leaf pairs which have no path anymore
+	Disconnected int `json:"disconnected"`
+	// Uplinks are the remaining uplinks of every leaf which did not fail, ordered by node name
+	Uplinks []*LeafUplinks `json:"uplinks"`
+	// Partitions are the groups of leafs which are connected to each other, a fabric
+	// without partitions has a single group
+	Partitions [][]string `json:"partitions"`
+}
+
+// LeafPairImpact are the paths between two leafs before and after a failure.
+type LeafPairImpact struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Paths and Hops are the equal-cost paths and hops after the failure, 0 if disconnected
+	Paths int `json:"paths"`
+	Hops  int `json:"hops"`
+	// PathsBefore and HopsBefore are the equal-cost paths and hops before the failure
+	PathsBefore int `json:"pathsBefore"`
+	HopsBefore  int `json:"hopsBefore"`
+}
+
+// Connected returns true if the leafs have a path after the failure.
+func (i *LeafPairImpact) Connected() bool { return i.Paths > 0 }
+
+// Changed returns true if the paths between the leafs changed by the failure.
+func (i *LeafPairImpact) Changed() bool {
+	return i.Paths != i.PathsBefore || i.Hops != i.HopsBefore
+}
+
+// LeafUplinks are the uplinks of a leaf before and after a failure, the bandwidth only
+// counts the links of which the speed of the leaf port is known from the platform catalog.
+type LeafUplinks struct {
+	Leaf            string  `json:"leaf"`
+	Links           int     `json:"links"`
+	LinksBefore     int     `json:"linksBefore"`
+	BandwidthGbps   float64 `json:"bandwidthGbps"`
+	BandwidthBefore float64 `json:"bandwidthGbpsBefore"`
+}
+
+// SimulateFailure returns the impact of the failure of the nodes and links on the leafs,
+// the fabric itself is not changed.
+func (f *fabric) SimulateFailure(failure *Failure) (*FailureReport, error) {
+	g := f.newPathGraph()
+	if err := f.applyFailure(g, failure); err != nil {
+		return nil, err
+	}
+
+	r := &FailureReport{
+		FailedNodes: []string{},
+		FailedLinks: []string{},
+		LeafPairs:   []*LeafPairImpact{},
+		Uplinks:     []*LeafUplinks{},
+		Partitions:  [][]string{},
+	}
+	for _, n := range f.GetNodes() {
+		if g.failedNodes[n.ID()] {
+			r.FailedNodes = append(r.FailedNodes, n.String())
+		}
+	}
+	sort.Strings(r.FailedNodes)
+	r.FailedLinks = sortedKeys(g.failedLinks)
+
+	leafs := []Node{}
+	for _, n := range f.nodesByLabel(positionSelector(topov1alpha1.PositionLeaf, nil)) {
+		if !g.failedNodes[n.ID()] {
+			leafs = append(leafs, n)
+		}
+	}
+	sort.Slice(leafs, func(i, j int) bool { return leafs[i].String() < leafs[j].String() })
+
+	before := f.newPathGraph().leafPaths(leafs)
+	after := g.leafPaths(leafs)
+	for i, b := range before {
+		a := after[i]
+		impact := &LeafPairImpact{
+			From:        a.From,
+			To:          a.To,
+			Paths:       a.Paths,
+			Hops:        a.Hops,
+			PathsBefore: b.Paths,
+			HopsBefore:  b.Hops,
+		}
+		if impact.PathsBefore > impact.Paths {
+			r.LostPaths += impact.PathsBefore - impact.Paths
+		}
+		if impact.PathsBefore > 0 && !impact.Connected() {
+			r.Disconnected++
+		}
+		r.LeafPairs = append(r.LeafPairs, impact)
+	}
+
+	for _, leaf := range leafs {
+		r.Uplinks = append(r.Uplinks, f.leafUplinks(g, leaf))
+	}
+	r.Partitions = partitions(leafs, after)
+	return r, nil
+}
+
+// applyFailure marks the failed nodes and links in the path graph, the links of a
+// failed node fail as well
+func (f *fabric) applyFailure(g *pathGraph, failure *Failure) error {
+	if failure == nil {
+		return nil
+	}
+	nodes := map[string]Node{}
+	for _, n := range f.GetNodes() {
+		nodes[n.String()] = n
+	}
+	for _, name := range failure.Nodes {
+		n, ok := nodes[name]
+		if !ok {
+			return fmt.Errorf("unknown node %s", name)
+		}
+		g.failedNodes[n.ID()] = true
+	}
+	if failure.NodeSelector != "" {
+		selector, err := labels.Parse(failure.NodeSelector)
+		if err != nil {
+			return fmt.Errorf("invalid node selector %q: %w", failure.NodeSelector, err)
+		}
+		for _, n := range f.nodesByLabel(selector) {
+			g.failedNodes[n.ID()] = true
+		}
+	}
+
+	links := map[string]Link{}
+	for _, l := range f.GetLinks() {
+		links[linkKey(l)] = l
+	}
+	for _, name := range failure.Links {
+		if _, ok := links[name]; !ok {
+			return fmt.Errorf("unknown link %s", name)
+		}
+		g.failedLinks[name] = true
+	}
+	var selector labels.Selector
+	if failure.LinkSelector != "" {
+		var err error
+		selector, err = labels.Parse(failure.LinkSelector)
+		if err != nil {
+			return fmt.Errorf("invalid link selector %q: %w", failure.LinkSelector, err)
+		}
+	}
+	for key, l := range links {
+		if (selector != nil && selector.Matches(l.GetLabels())) ||
+			g.failedNodes[l.From().ID()] || g.failedNodes[l.To().ID()] {
+			g.failedLinks[key] = true
+		}
+	}
+	return nil
+}
+
+// leafUplinks returns the uplinks of the leaf before and after the failure, the uplinks
+// are the links to the nodes of a higher tier
+func (f *fabric) leafUplinks(g *pathGraph, leaf Node) *LeafUplinks {
+	u := &LeafUplinks{Leaf: leaf.String()}
+	for _, l := range f.GetLinks() {
+		var peer Node
+		switch leaf.ID() {
+		case l.From().ID():
+			peer = l.To().(Node)
+		case l.To().ID():
+			peer = l.From().(Node)
+		default:
+			continue
+		}
+		if getLevel(peer) >= getLevel(leaf) {
+			continue
+		}
+		bandwidth := 0.0
+		if port, err := strconv.Atoi(l.GetEndpointLabel(leaf.String(), KeyPort)); err == nil {
+			bandwidth = speedGbps(leaf.GetInterfaceSpeed(uint32(port)))
+		}
+		u.LinksBefore++
+		u.BandwidthBefore += bandwidth
+		if !g.failedLinks[linkKey(l)] {
+			u.Links++
+			u.BandwidthGbps += bandwidth
+		}
+	}
+	return u
+}
+
+// speedGbps returns the speed in Gbps, e.g. 100G is 100 and 1T is 1000, 0 if unknown
+func speedGbps(speed string) float64 {
+	units := map[string]float64{"M": 0.001, "G": 1, "T": 1000}
+	if len(speed) < 2 {
+		return 0
+	}
+	unit, ok := units[strings.ToUpper(speed[len(speed)-1:])]
+	if !ok {
+		return 0
+	}
+	v, err := strconv.ParseFloat(speed[:len(speed)-1], 64)
+	if err != nil {
+		return 0
+	}
+	return v * unit
+}
+
+// partitions groups the leafs which have a path to each other, ordered by name
+func partitions(leafs []Node, paths []*PathReport) [][]string {
+	group := map[string]string{}
+	for _, leaf := range leafs {
+		group[leaf.String()] = leaf.String()
+	}
+	var find func(name string) string
+	find = func(name string) string {
+		if group[name] != name {
+			group[name] = find(group[name])
+		}
+		return group[name]
+	}
+	for _, p := range paths {
+		if p.Paths > 0 {
+			a, b := find(p.From), find(p.To)
+			if a > b {
+				a, b = b, a
+			}
+			group[b] = a
+		}
+	}
+
+	members := map[string][]string{}
+	for _, leaf := range leafs {
+		root := find(leaf.String())
+		members[root] = append(members[root], leaf.String())
+	}
+	result := [][]string{}
+	for _, root := range sortedKeys(members) {
+		result = append(result, members[root])
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package fabric
+
+import (
+	"testing"
+)
+
+func TestSimulateFailure(t *testing.T) {
+	// the example has 8 leafs in 2 pods, intra-pod leaf pairs have 2 * 4 paths and
+	// inter-pod leaf pairs 2 * 2 * 16 paths
+	f := mustNewTestFabric(t, exampleTemplate(t))
+
+	// leafLink is the first link between pod1-leaf1 and pod1-spine1
+	var leafLink string
+	for _, l := range f.GetLinks() {
+		if (l.FromNodeName() == "pod1-spine1" && l.ToNodeName() == "pod1-leaf1") ||
+			(l.FromNodeName() == "pod1-leaf1" && l.ToNodeName() == "pod1-spine1") {
+			if leafLink == "" || linkKey(l) < leafLink {
+				leafLink = linkKey(l)
+			}
+		}
+	}
+
+	tests := map[string]struct {
+		failure      *Failure
+		failedNodes  int
+		failedLinks  int
+		lostPaths    int
+		disconnected int
+		partitions   int
+		// uplinks are the links of pod1-leaf1 after the failure, 4 before
+		uplinks int
+		wantErr bool
+	}{
+		"no failure": {
+			failure:    &Failure{},
+			partitions: 1,
+			uplinks:    4,
+		},
+		"superspine plane": {
+			failure:     &Failure{NodeSelector: "position=superspine,planeIndex=1"},
+			failedNodes: 2,
+			failedLinks: 2 * 2 * 2,
+			lostPaths:   16 * 32,
+			partitions:  1,
+			uplinks:     4,
+		},
+		"all superspines": {
+			failure:      &Failure{NodeSelector: "position=superspine"},
+			failedNodes:  4,
+			failedLinks:  4 * 2 * 2,
+			lostPaths:    16 * 64,
+			disconnected: 16,
+			partitions:   2,
+			uplinks:      4,
+		},
+		"spine": {
+			failure:     &Failure{Nodes: []string{"pod1-spine1"}},
+			failedNodes: 1,
+			failedLinks: 2*2 + 4*2,
+			lostPaths:   6*4 + 16*32,
+			partitions:  1,
+			uplinks:     2,
+		},
+		"link": {
+			failure:     &Failure{Links: []string{leafLink}},
+			failedLinks: 1,
+			lostPaths:   3*2 + 4*16,
+			partitions:  1,
+			uplinks:     3,
+		},
+		"failed leaf is not reported": {
+			failure:     &Failure{Nodes: []string{"pod2-leaf4"}},
+			failedNodes: 1,
+			failedLinks: 4,
+			partitions:  1,
+			uplinks:     4,
+		},
+		"unknown node": {
+			failure: &Failure{Nodes: []string{"pod3-spine1"}},
+			wantErr: true,
+		},
+		"unknown link": {
+			failure: &Failure{Links: []string{"pod1-leaf1:int-1/1--pod1-spine1:int-1/1"}},
+			wantErr: true,
+		},
+		"invalid selector": {
+			failure: &Failure{LinkSelector: "position in"},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := f.SimulateFailure(tc.failure)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("SimulateFailure() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if len(r.FailedNodes) != tc.failedNodes || len(r.FailedLinks) != tc.failedLinks {
+				t.Errorf("failed nodes and links = %d, %d, want %d, %d",
+					len(r.FailedNodes), len(r.FailedLinks), tc.failedNodes, tc.failedLinks)
+			}
+			if r.LostPaths != tc.lostPaths || r.Disconnected != tc.disconnected || len(r.Partitions) != tc.partitions {
+				t.Errorf("lost paths, disconnected and partitions = %d, %d, %d, want %d, %d, %d",
+					r.LostPaths, r.Disconnected, len(r.Partitions), tc.lostPaths, tc.disconnected, tc.partitions)
+			}
+			for _, u := range r.Uplinks {
+				if u.Leaf != "pod1-leaf1" {
+					continue
+				}
+				if u.LinksBefore != 4 || u.Links != tc.uplinks {
+					t.Errorf("pod1-leaf1 uplinks = %d of %d, want %d of 4", u.Links, u.LinksBefore, tc.uplinks)
+				}
+				if u.BandwidthBefore != 400 || u.BandwidthGbps != float64(tc.uplinks)*100 {
+					t.Errorf("pod1-leaf1 bandwidth = %.0fG of %.0fG, want %dG of 400G", u.BandwidthGbps, u.BandwidthBefore, tc.uplinks*100)
+				}
+			}
+		})
+	}
+}
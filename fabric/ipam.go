@@ -0,0 +1,383 @@
+package fabric
+
+import (
+	"fmt"
+	"math/big"
+	"net/netip"
+	"strconv"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultIPv4LinkPrefixLength = 31
+	defaultIPv6LinkPrefixLength = 127
+	defaultMaxLinksPerNode      = 128
+	defaultMaxLeafsPerPod       = 32
+)
+
+const (
+	// AnnotationIPv4LinkPrefix selects the IPAMConfig IPv4Prefix in the template
+	AnnotationIPv4LinkPrefix = "fabric.henderiw.io/ipv4-link-prefix"
+	// AnnotationIPv6LinkPrefix selects the IPAMConfig IPv6Prefix in the template
+	AnnotationIPv6LinkPrefix = "fabric.henderiw.io/ipv6-link-prefix"
+	// AnnotationIPv4LinkPrefixLength selects the IPAMConfig IPv4PrefixLength in the template
+	AnnotationIPv4LinkPrefixLength = "fabric.henderiw.io/ipv4-link-prefix-length"
+	// AnnotationIPv6LinkPrefixLength selects the IPAMConfig IPv6PrefixLength in the template
+	AnnotationIPv6LinkPrefixLength = "fabric.henderiw.io/ipv6-link-prefix-length"
+	// AnnotationMaxLinksPerNode selects the IPAMConfig MaxLinksPerNode in the template
+	AnnotationMaxLinksPerNode = "fabric.henderiw.io/max-links-per-node"
+	// AnnotationMaxLeafsPerPod selects the IPAMConfig MaxLeafsPerPod in the template
+	AnnotationMaxLeafsPerPod = "fabric.henderiw.io/max-leafs-per-pod"
+	// AnnotationSystemPools declares the IPAMConfig SystemPools in the template, as a JSON or
+	// YAML map of position to SystemPool
+	AnnotationSystemPools = "fabric.henderiw.io/system-pools"
+)
+
+// IPAMConfig specifies the underlay prefixes used to address the fabric links.
+type IPAMConfig struct {
+	// IPv4Prefix is the IPv4 prefix the link subnets are allocated from, e.g. 10.0.0.0/16
+	IPv4Prefix string `json:"ipv4Prefix,omitempty"`
+	// IPv6Prefix is the IPv6 prefix the link subnets are allocated from, e.g. 2001:db8::/48
+	IPv6Prefix string `json:"ipv6Prefix,omitempty"`
+	// IPv4PrefixLength is the IPv4 link subnet length, 31 (default) or 30
+	IPv4PrefixLength int `json:"ipv4PrefixLength,omitempty"`
+	// IPv6PrefixLength is the IPv6 link subnet length, 127 (default) or 64
+	IPv6PrefixLength int `json:"ipv6PrefixLength,omitempty"`
+	// MaxLinksPerNode is the number of link subnets reserved per node, default 128
+	MaxLinksPerNode uint32 `json:"maxLinksPerNode,omitempty"`
+	// MaxLeafsPerPod is the number of leafs reserved per pod for the leaf ISL subnets and
+	// the spine links of collapsed spines, default 32
+	MaxLeafsPerPod uint32 `json:"maxLeafsPerPod,omitempty"`
+	// SystemPools are the system/loopback pools per position (superspine, spine, leaf, borderleaf)
+	SystemPools map[string]*SystemPool `json:"systemPools,omitempty"`
+}
+
+// SystemPool specifies the prefixes the system addresses of the nodes in a tier are allocated from.
+type SystemPool struct {
+	// IPv4Prefix is the IPv4 prefix of the system addresses, the router-id is the IPv4 system address
+	IPv4Prefix string `json:"ipv4Prefix,omitempty"`
+	// IPv6Prefix is the IPv6 prefix of the system addresses
+	IPv6Prefix string `json:"ipv6Prefix,omitempty"`
+	// MaxNodesPerPod is the number of addresses reserved per pod for spines and leafs,
+	// default is maxLeafsPerPod for leafs and maxSpinesPerPod for the other tiers
+	MaxNodesPerPod uint32 `json:"maxNodesPerPod,omitempty"`
+}
+
+// WithIPAM specifies the prefixes used to allocate the link and system addresses.
+func WithIPAM(c *IPAMConfig) Option {
+	return func(f Fabric) {
+		f.SetIPAM(c)
+	}
+}
+
+func (f *fabric) SetIPAM(c *IPAMConfig) { f.ipam = c }
+
+// ipamOptions returns the IPAM option selected by the template annotations
+func ipamOptions(annotations map[string]string) ([]Option, error) {
+	c := &IPAMConfig{}
+	found := false
+	for key, v := range map[string]*string{
+		AnnotationIPv4LinkPrefix: &c.IPv4Prefix,
+		AnnotationIPv6LinkPrefix: &c.IPv6Prefix,
+	} {
+		if s, ok := annotations[key]; ok {
+			*v = s
+			found = true
+		}
+	}
+	for key, v := range map[string]*int{
+		AnnotationIPv4LinkPrefixLength: &c.IPv4PrefixLength,
+		AnnotationIPv6LinkPrefixLength: &c.IPv6PrefixLength,
+	} {
+		s, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s must be a number: %w", key, err)
+		}
+		*v = i
+		found = true
+	}
+	for key, v := range map[string]*uint32{
+		AnnotationMaxLinksPerNode: &c.MaxLinksPerNode,
+		AnnotationMaxLeafsPerPod:  &c.MaxLeafsPerPod,
+	} {
+		s, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		i, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s must be a number: %w", key, err)
+		}
+		*v = uint32(i)
+		found = true
+	}
+	if v, ok := annotations[AnnotationSystemPools]; ok {
+		if err := yaml.Unmarshal([]byte(v), &c.SystemPools); err != nil {
+			return nil, fmt.Errorf("annotation %s must be a map of system pools: %w", AnnotationSystemPools, err)
+		}
+		found = true
+	}
+	if !found {
+		return nil, nil
+	}
+	return []Option{WithIPAM(c)}, nil
+}
+
+// allocateLinkAddresses assigns a point-to-point subnet to every link and stores
+// the endpoint addresses as link labels.
+// The subnet is derived from the upper node of the link (the from node) and its
+// interface index, so allocations stay stable when nodes or pods are appended:
+// subnet index -> nodeOrdinal(from) * maxLinksPerNode + ifIndex - 1
+func (f *fabric) allocateLinkAddresses() error {
+	if f.ipam == nil {
+		return nil
+	}
+	maxLinks := f.ipam.MaxLinksPerNode
+	if maxLinks == 0 {
+		maxLinks = defaultMaxLinksPerNode
+	}
+
+	pools := make([]*linkPool, 0, 2)
+	if f.ipam.IPv4Prefix != "" {
+		p, err := newLinkPool(KeyIPv4, f.ipam.IPv4Prefix, f.ipam.IPv4PrefixLength, defaultIPv4LinkPrefixLength)
+		if err != nil {
+			return err
+		}
+		pools = append(pools, p)
+	}
+	if f.ipam.IPv6Prefix != "" {
+		p, err := newLinkPool(KeyIPv6, f.ipam.IPv6Prefix, f.ipam.IPv6PrefixLength, defaultIPv6LinkPrefixLength)
+		if err != nil {
+			return err
+		}
+		pools = append(pools, p)
+	}
+
+	for _, l := range f.GetLinks() {
+		from := l.From().(Node)
+		to := l.To().(Node)
+		// server links are access ports without fabric addresses
+		if isServer(from) || isServer(to) {
+			continue
+		}
+
+		ifIndex, err := strconv.Atoi(l.GetEndpointLabel(from.String(), KeyIfIndex))
+		if err != nil {
+			return fmt.Errorf("link %s has no interface index: %w", l.String(), err)
+		}
+		if ifIndex < 1 || uint32(ifIndex) > maxLinks {
+			return fmt.Errorf("link %s interface index %d exceeds maxLinksPerNode %d", l.String(), ifIndex, maxLinks)
+		}
+		ordinal, err := nodeOrdinal(from, f.settings, f.GetMaxLeafsPerPod())
+		if err != nil {
+			return err
+		}
+		subnetIndex := ordinal*uint64(maxLinks) + uint64(ifIndex-1)
+
+		for _, p := range pools {
+			fromAddr, toAddr, err := p.get(subnetIndex)
+			if err != nil {
+				return fmt.Errorf("link %s: %w", l.String(), err)
+			}
+			l.UpdateLabel(map[string]string{
+				EndpointLabelKey(from.String(), p.key): fromAddr.String(),
+				EndpointLabelKey(to.String(), p.key):   toAddr.String(),
+			})
+		}
+	}
+	return nil
+}
+
+// allocateSystemAddresses assigns the system IPv4/IPv6 addresses and router-id
+// to every node with a system pool for its position. The address is derived from
+// tierOrdinal, so re-renders never reshuffle addresses.
+func (f *fabric) allocateSystemAddresses() error {
+	if f.ipam == nil || len(f.ipam.SystemPools) == 0 {
+		return nil
+	}
+	for _, n := range f.GetNodes() {
+		pool, ok := f.ipam.SystemPools[n.GetPosition()]
+		if !ok {
+			continue
+		}
+		maxNodesPerPod := pool.MaxNodesPerPod
+		if maxNodesPerPod == 0 {
+			switch {
+			case n.GetPosition() == string(topov1alpha1.PositionLeaf):
+				maxNodesPerPod = f.GetMaxLeafsPerPod()
+			case f.settings != nil:
+				maxNodesPerPod = f.settings.MaxSpinesPerPod
+			}
+		}
+		ordinal, err := tierOrdinal(n, maxNodesPerPod)
+		if err != nil {
+			return err
+		}
+
+		label := map[string]string{}
+		if pool.IPv4Prefix != "" {
+			a, err := nthAddress(pool.IPv4Prefix, ordinal)
+			if err != nil {
+				return fmt.Errorf("node %s: %w", n.String(), err)
+			}
+			label[KeySystemIPv4] = a.String()
+			label[KeyRouterID] = a.String()
+		}
+		if pool.IPv6Prefix != "" {
+			a, err := nthAddress(pool.IPv6Prefix, ordinal)
+			if err != nil {
+				return fmt.Errorf("node %s: %w", n.String(), err)
+			}
+			label[KeySystemIPv6] = a.String()
+		}
+		n.UpdateLabel(label)
+	}
+	return nil
+}
+
+// nthAddress returns host address i of the prefix, skipping the network address
+func nthAddress(prefix string, i uint64) (netip.Addr, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	subnet, err := nthSubnet(p.Masked(), p.Addr().BitLen(), i+1)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return subnet.Addr(), nil
+}
+
+// tierOrdinal returns a unique and stable ordinal for a node within its position,
+// which does not change when nodes or pods are appended to the fabric:
+// superspine -> (relativeNodeIndex - 1) * maxNodesPerPod + planeIndex - 1
+// borderleaf -> relativeNodeIndex - 1
+// spine/leaf -> (podIndex - 1) * maxNodesPerPod + relativeNodeIndex - 1
+func tierOrdinal(n Node, maxNodesPerPod uint32) (uint64, error) {
+	relativeIndex, err := strconv.Atoi(n.GetRelativeNodeIndex())
+	if err != nil {
+		return 0, err
+	}
+	if n.GetPosition() == string(topov1alpha1.PositionBorderLeaf) {
+		return uint64(relativeIndex - 1), nil
+	}
+
+	if maxNodesPerPod == 0 {
+		return 0, fmt.Errorf("node %s: maxSpinesPerPod must be set to allocate addresses", n.String())
+	}
+	if n.GetPosition() == string(topov1alpha1.PositionSuperspine) {
+		planeIndex, err := strconv.Atoi(n.GetPlaneIndex())
+		if err != nil {
+			return 0, err
+		}
+		if uint32(planeIndex) > maxNodesPerPod {
+			return 0, fmt.Errorf("node %s: plane index %d exceeds %d planes", n.String(), planeIndex, maxNodesPerPod)
+		}
+		return uint64(relativeIndex-1)*uint64(maxNodesPerPod) + uint64(planeIndex-1), nil
+	}
+	podIndex, err := strconv.Atoi(n.GetPodIndex())
+	if err != nil {
+		return 0, err
+	}
+	if uint32(relativeIndex) > maxNodesPerPod {
+		return 0, fmt.Errorf("node %s: relative node index %d exceeds %d nodes per pod", n.String(), relativeIndex, maxNodesPerPod)
+	}
+	return uint64(podIndex-1)*uint64(maxNodesPerPod) + uint64(relativeIndex-1), nil
+}
+
+// nodeOrdinal returns a unique and stable ordinal for a node across all positions,
+// by interleaving the tierOrdinal of the positions, the leafs are only the upper
+// node of the leaf ISLs and use maxLeafsPerPod:
+// superspine -> 4 * tierOrdinal
+// borderleaf -> 4 * tierOrdinal + 1
+// spine      -> 4 * tierOrdinal + 2
+// leaf       -> 4 * tierOrdinal + 3
+func nodeOrdinal(n Node, s *topov1alpha1.FabricTemplateSettings, maxLeafsPerPod uint32) (uint64, error) {
+	if s == nil {
+		return 0, fmt.Errorf("node %s: maxSpinesPerPod must be set to allocate addresses", n.String())
+	}
+	maxNodesPerPod := s.MaxSpinesPerPod
+	if n.GetPosition() == string(topov1alpha1.PositionLeaf) {
+		maxNodesPerPod = maxLeafsPerPod
+	}
+	ordinal, err := tierOrdinal(n, maxNodesPerPod)
+	if err != nil {
+		return 0, err
+	}
+	switch n.GetPosition() {
+	case string(topov1alpha1.PositionSuperspine):
+		return 4 * ordinal, nil
+	case string(topov1alpha1.PositionBorderLeaf):
+		return 4*ordinal + 1, nil
+	case string(topov1alpha1.PositionSpine):
+		return 4*ordinal + 2, nil
+	default:
+		return 4*ordinal + 3, nil
+	}
+}
+
+// linkPool allocates point-to-point subnets of a fixed length from a prefix
+type linkPool struct {
+	key    string
+	prefix netip.Prefix
+	length int
+}
+
+func newLinkPool(key, prefix string, length, defaultLength int) (*linkPool, error) {
+	p, err := netip.ParsePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		length = defaultLength
+	}
+	if length < p.Bits() || length > p.Addr().BitLen() {
+		return nil, fmt.Errorf("link prefix length /%d is not valid for prefix %s", length, p.String())
+	}
+	return &linkPool{
+		key:    key,
+		prefix: p.Masked(),
+		length: length,
+	}, nil
+}
+
+// get returns the addresses of both link endpoints in subnet index i
+// the first usable address is assigned to the from node, the second one to the to node
+func (p *linkPool) get(i uint64) (netip.Prefix, netip.Prefix, error) {
+	subnet, err := nthSubnet(p.prefix, p.length, i)
+	if err != nil {
+		return netip.Prefix{}, netip.Prefix{}, err
+	}
+	from := subnet.Addr()
+	// only /31 and /127 use the network address for the endpoints
+	if subnet.Addr().BitLen()-p.length > 1 {
+		from = from.Next()
+	}
+	to := from.Next()
+	return netip.PrefixFrom(from, p.length), netip.PrefixFrom(to, p.length), nil
+}
+
+// nthSubnet returns subnet i of the given length within the prefix
+func nthSubnet(prefix netip.Prefix, length int, i uint64) (netip.Prefix, error) {
+	bitLen := prefix.Addr().BitLen()
+	if new(big.Int).SetUint64(i).BitLen() > length-prefix.Bits() {
+		return netip.Prefix{}, fmt.Errorf("subnet %d of length /%d does not fit in prefix %s", i, length, prefix.String())
+	}
+	offset := new(big.Int).Lsh(new(big.Int).SetUint64(i), uint(bitLen-length))
+	addr := new(big.Int).SetBytes(prefix.Addr().AsSlice())
+	addr.Add(addr, offset)
+
+	b := make([]byte, bitLen/8)
+	addr.FillBytes(b)
+	a, ok := netip.AddrFromSlice(b)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("invalid address for subnet %d in prefix %s", i, prefix.String())
+	}
+	return netip.PrefixFrom(a, length), nil
+}
@@ -0,0 +1,316 @@
+package fabric
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+// assertUniqueSubnets asserts every link of the fabric has a subnet of its own
+func assertUniqueSubnets(t *testing.T, f Fabric, key string) {
+	t.Helper()
+	owners := map[netip.Prefix]string{}
+	for _, l := range f.GetLinks() {
+		if isServer(l.From().(Node)) || isServer(l.To().(Node)) {
+			continue
+		}
+		addr := l.GetEndpointLabel(l.FromNodeName(), key)
+		p, err := netip.ParsePrefix(addr)
+		if err != nil {
+			t.Fatalf("link %s has no %s address: %q", linkKey(l), key, addr)
+		}
+		if owner, ok := owners[p.Masked()]; ok {
+			t.Errorf("links %s and %s share subnet %s", owner, linkKey(l), p.Masked())
+		}
+		owners[p.Masked()] = linkKey(l)
+	}
+}
+
+func TestLinkSubnetsUnique(t *testing.T) {
+	ipam := &IPAMConfig{IPv4Prefix: "10.0.0.0/16", IPv6Prefix: "2001:db8::/48"}
+	tests := map[string]struct {
+		template string
+		opts     []Option
+	}{
+		"example": {
+			template: exampleTemplate(t),
+			opts:     []Option{WithIPAM(ipam)},
+		},
+		"collapsed spines": {
+			template: collapsedTemplate,
+			opts:     []Option{WithIPAM(ipam)},
+		},
+		"annotations": {
+			template: annotatedExampleTemplate(t,
+				AnnotationIPv4LinkPrefix+": 10.0.0.0/16",
+				AnnotationIPv6LinkPrefix+": 2001:db8::/48",
+			),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := mustNewTestFabric(t, tc.template, tc.opts...)
+			assertUniqueSubnets(t, f, KeyIPv4)
+			assertUniqueSubnets(t, f, KeyIPv6)
+		})
+	}
+}
+
+func TestSystemAddresses(t *testing.T) {
+	ipam := &IPAMConfig{
+		SystemPools: map[string]*SystemPool{
+			"superspine": {IPv4Prefix: "10.254.0.0/24"},
+			"spine":      {IPv4Prefix: "10.254.1.0/24", IPv6Prefix: "2001:db8:ff::/64"},
+			"leaf":       {IPv4Prefix: "10.254.2.0/24", IPv6Prefix: "2001:db8:fe::/64"},
+		},
+	}
+	tests := map[string]struct {
+		node string
+		want string
+	}{
+		"superspine": {node: "plane2-superspine2", want: "10.254.0.4"},
+		"spine":      {node: "pod2-spine1", want: "10.254.1.3"},
+		"leaf":       {node: "pod1-leaf4", want: "10.254.2.4"},
+		"second pod": {node: "pod2-leaf1", want: "10.254.2.33"},
+	}
+	f := mustNewTestFabric(t, exampleTemplate(t), WithIPAM(ipam))
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := getTestNode(t, f, tc.node).GetLabels()[KeySystemIPv4]; got != tc.want {
+				t.Errorf("%s system address = %s, want %s", tc.node, got, tc.want)
+			}
+		})
+	}
+
+	seen := map[string]string{}
+	for _, n := range f.GetNodes() {
+		for _, key := range []string{KeySystemIPv4, KeySystemIPv6} {
+			a, ok := n.GetLabels()[key]
+			if !ok {
+				continue
+			}
+			if owner, ok := seen[a]; ok {
+				t.Errorf("nodes %s and %s share system address %s", owner, n.String(), a)
+			}
+			seen[a] = n.String()
+		}
+	}
+}
+
+func TestIPAMAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations map[string]string
+		want        *IPAMConfig
+		wantErr     bool
+	}{
+		"none": {
+			annotations: map[string]string{},
+		},
+		"link prefixes": {
+			annotations: map[string]string{
+				AnnotationIPv4LinkPrefix:       "10.0.0.0/16",
+				AnnotationIPv6LinkPrefix:       "2001:db8::/48",
+				AnnotationIPv4LinkPrefixLength: "30",
+				AnnotationIPv6LinkPrefixLength: "64",
+				AnnotationMaxLinksPerNode:      "64",
+				AnnotationMaxLeafsPerPod:       "16",
+			},
+			want: &IPAMConfig{
+				IPv4Prefix:       "10.0.0.0/16",
+				IPv6Prefix:       "2001:db8::/48",
+				IPv4PrefixLength: 30,
+				IPv6PrefixLength: 64,
+				MaxLinksPerNode:  64,
+				MaxLeafsPerPod:   16,
+			},
+		},
+		"system pools": {
+			annotations: map[string]string{
+				AnnotationSystemPools: `
+spine:
+  ipv4Prefix: 10.254.1.0/24
+leaf:
+  ipv4Prefix: 10.254.2.0/24
+  ipv6Prefix: 2001:db8:fe::/64
+  maxNodesPerPod: 64
+`,
+			},
+			want: &IPAMConfig{
+				SystemPools: map[string]*SystemPool{
+					"spine": {IPv4Prefix: "10.254.1.0/24"},
+					"leaf":  {IPv4Prefix: "10.254.2.0/24", IPv6Prefix: "2001:db8:fe::/64", MaxNodesPerPod: 64},
+				},
+			},
+		},
+		"invalid system pools": {
+			annotations: map[string]string{AnnotationSystemPools: "- spine"},
+			wantErr:     true,
+		},
+		"invalid prefix length": {
+			annotations: map[string]string{AnnotationIPv4LinkPrefixLength: "thirty"},
+			wantErr:     true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts, err := ipamOptions(tc.annotations)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ipamOptions() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			f := &fabric{}
+			for _, o := range opts {
+				o(f)
+			}
+			if !reflect.DeepEqual(f.ipam, tc.want) {
+				t.Errorf("ipam = %+v, want %+v", f.ipam, tc.want)
+			}
+		})
+	}
+}
+
+// newOrdinalNode returns a node of the position with the indexes
+func newOrdinalNode(t *testing.T, position topov1alpha1.Position, podOrPlane, relativeIndex uint32) Node {
+	t.Helper()
+	ni := &nodeInfo{
+		position:          position,
+		relativeNodeIndex: relativeIndex,
+		vendorInfo:        &topov1alpha1.FabricTierVendorInfo{},
+	}
+	if position == topov1alpha1.PositionSuperspine {
+		ni.planeIndex = podOrPlane
+	} else {
+		ni.podIndex = podOrPlane
+	}
+	n, err := NewNode(ni)
+	if err != nil {
+		t.Fatalf("cannot create node: %s", err)
+	}
+	return n
+}
+
+func TestOrdinals(t *testing.T) {
+	settings := &topov1alpha1.FabricTemplateSettings{MaxSpinesPerPod: 4}
+	tests := map[string]struct {
+		position      topov1alpha1.Position
+		podOrPlane    uint32
+		relativeIndex uint32
+		wantTier      uint64
+		wantNode      uint64
+		wantErr       bool
+	}{
+		"first superspine": {
+			position: topov1alpha1.PositionSuperspine, podOrPlane: 1, relativeIndex: 1,
+			wantTier: 0, wantNode: 0,
+		},
+		"superspine interleaves the planes": {
+			position: topov1alpha1.PositionSuperspine, podOrPlane: 3, relativeIndex: 2,
+			wantTier: 6, wantNode: 24,
+		},
+		"superspine plane exceeds maxSpinesPerPod": {
+			position: topov1alpha1.PositionSuperspine, podOrPlane: 5, relativeIndex: 1,
+			wantErr: true,
+		},
+		"borderleaf": {
+			position: topov1alpha1.PositionBorderLeaf, relativeIndex: 3,
+			wantTier: 2, wantNode: 9,
+		},
+		"spine": {
+			position: topov1alpha1.PositionSpine, podOrPlane: 2, relativeIndex: 2,
+			wantTier: 5, wantNode: 22,
+		},
+		"spine exceeds maxSpinesPerPod": {
+			position: topov1alpha1.PositionSpine, podOrPlane: 1, relativeIndex: 5,
+			wantErr: true,
+		},
+		"leaf uses maxLeafsPerPod": {
+			position: topov1alpha1.PositionLeaf, podOrPlane: 2, relativeIndex: 10,
+			wantTier: 41, wantNode: 167,
+		},
+		"leaf exceeds maxLeafsPerPod": {
+			position: topov1alpha1.PositionLeaf, podOrPlane: 1, relativeIndex: 33,
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			n := newOrdinalNode(t, tc.position, tc.podOrPlane, tc.relativeIndex)
+			maxNodesPerPod := settings.MaxSpinesPerPod
+			if tc.position == topov1alpha1.PositionLeaf {
+				maxNodesPerPod = defaultMaxLeafsPerPod
+			}
+			gotTier, err := tierOrdinal(n, maxNodesPerPod)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("tierOrdinal() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			gotNode, err := nodeOrdinal(n, settings, defaultMaxLeafsPerPod)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("nodeOrdinal() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if gotTier != tc.wantTier {
+				t.Errorf("tierOrdinal() = %d, want %d", gotTier, tc.wantTier)
+			}
+			if gotNode != tc.wantNode {
+				t.Errorf("nodeOrdinal() = %d, want %d", gotNode, tc.wantNode)
+			}
+		})
+	}
+}
+
+func TestLinkAddresses(t *testing.T) {
+	tests := map[string]struct {
+		ipam     *IPAMConfig
+		link     string
+		node     string
+		wantIPv4 string
+		wantIPv6 string
+	}{
+		// superspine ordinal 0, ifIndex 1 -> subnet 0
+		"superspine": {
+			ipam:     &IPAMConfig{IPv4Prefix: "10.0.0.0/16", IPv6Prefix: "2001:db8::/48"},
+			link:     "plane1-superspine1:int-1/1--pod1-spine1:int-1/25",
+			node:     "pod1-spine1",
+			wantIPv4: "10.0.0.1/31",
+			wantIPv6: "2001:db8::1/127",
+		},
+		// spine pod1-spine2 ordinal 4 * 1 + 2 = 6, ifIndex 3 -> subnet 6 * 128 + 2
+		"spine": {
+			ipam:     &IPAMConfig{IPv4Prefix: "10.0.0.0/16", IPv6Prefix: "2001:db8::/48"},
+			link:     "pod1-leaf2:int-1/29--pod1-spine2:int-1/3",
+			node:     "pod1-spine2",
+			wantIPv4: "10.0.6.4/31",
+			wantIPv6: "2001:db8::604/127",
+		},
+		"prefix lengths": {
+			ipam:     &IPAMConfig{IPv4Prefix: "10.0.0.0/16", IPv4PrefixLength: 30, IPv6Prefix: "2001:db8::/48", IPv6PrefixLength: 64, MaxLinksPerNode: 64},
+			link:     "pod1-leaf2:int-1/29--pod1-spine2:int-1/3",
+			node:     "pod1-leaf2",
+			wantIPv4: "10.0.6.10/30",
+			wantIPv6: "2001:db8:0:182::2/64",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := mustNewTestFabric(t, exampleTemplate(t), WithIPAM(tc.ipam))
+			var l Link
+			for _, fl := range f.GetLinks() {
+				if linkKey(fl) == tc.link {
+					l = fl
+				}
+			}
+			if l == nil {
+				t.Fatalf("link %s not found", tc.link)
+			}
+			if got := l.GetEndpointLabel(tc.node, KeyIPv4); got != tc.wantIPv4 {
+				t.Errorf("%s ipv4 = %s, want %s", tc.node, got, tc.wantIPv4)
+			}
+			if got := l.GetEndpointLabel(tc.node, KeyIPv6); got != tc.wantIPv6 {
+				t.Errorf("%s ipv6 = %s, want %s", tc.node, got, tc.wantIPv6)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package fabric
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+const (
+	// AnnotationLeafGroupSize selects the LeafGroupConfig Size in the template
+	AnnotationLeafGroupSize = "fabric.henderiw.io/leaf-group-size"
+	// AnnotationLeafISLsPerPeer selects the LeafGroupConfig ISLsPerPeer in the template
+	AnnotationLeafISLsPerPeer = "fabric.henderiw.io/leaf-isls-per-peer"
+	// AnnotationLeafISLInterfaceStart selects the LeafGroupConfig ISLInterfaceStart in the template
+	AnnotationLeafISLInterfaceStart = "fabric.henderiw.io/leaf-isl-interface-start"
+)
+
+// LeafGroupConfig groups the leafs of a pod into redundancy groups, e.g. MLAG pairs or
+// EVPN multihoming groups. The leafs are grouped by relative index, so with a size of 2
+// leaf1 and leaf2 are group 1, leaf3 and leaf4 are group 2, etc.
+type LeafGroupConfig struct {
+	// Size is the number of leafs per group, the leafs of every pod must be a multiple of it
+	Size uint32 `json:"size,omitempty"`
+	// ISLsPerPeer is the number of inter-switch links between every two leafs of a group,
+	// 0 creates no ISLs, e.g. for EVPN multihoming
+	ISLsPerPeer uint32 `json:"islsPerPeer,omitempty"`
+	// ISLInterfaceStart is the first interface index of the ISLs, the ISLs use their
+	// own range so they don't overlap with the uplinks
+	ISLInterfaceStart uint32 `json:"islInterfaceStart,omitempty"`
+}
+
+// WithLeafGroups groups the leafs of every pod into redundancy groups.
+func WithLeafGroups(c *LeafGroupConfig) Option {
+	return func(f Fabric) {
+		f.SetLeafGroups(c)
+	}
+}
+
+func (f *fabric) SetLeafGroups(c *LeafGroupConfig) {
+	f.leafGroups = c
+	if c != nil {
+		f.SetWirer(TierPairLeafLeaf, NewLeafISLWirer(c.ISLsPerPeer, c.ISLInterfaceStart))
+	}
+}
+
+// leafGroupOptions returns the leaf group option selected by the template annotations
+func leafGroupOptions(annotations map[string]string) ([]Option, error) {
+	if _, ok := annotations[AnnotationLeafGroupSize]; !ok {
+		return nil, nil
+	}
+	c := &LeafGroupConfig{}
+	for key, v := range map[string]*uint32{
+		AnnotationLeafGroupSize:         &c.Size,
+		AnnotationLeafISLsPerPeer:       &c.ISLsPerPeer,
+		AnnotationLeafISLInterfaceStart: &c.ISLInterfaceStart,
+	} {
+		s, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		i, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s must be a number: %w", key, err)
+		}
+		*v = uint32(i)
+	}
+	return []Option{WithLeafGroups(c)}, nil
+}
+
+// groupLeafs labels every leaf with its redundancy group within the pod
+func (f *fabric) groupLeafs() error {
+	if f.leafGroups == nil {
+		return nil
+	}
+	size := f.leafGroups.Size
+	if size < 2 {
+		return fmt.Errorf("leaf group size %d must be at least 2", size)
+	}
+	if f.leafGroups.ISLsPerPeer > 0 && f.leafGroups.ISLInterfaceStart == 0 {
+		return fmt.Errorf("leaf groups with ISLs require an ISL interface start")
+	}
+
+	for _, podIndex := range f.GetPodIndexes() {
+		leafs := f.nodesByLabel(positionSelector(topov1alpha1.PositionLeaf, map[string]string{
+			KeyPodIndex: strconv.Itoa(int(podIndex)),
+		}))
+		if len(leafs)%int(size) != 0 {
+			return fmt.Errorf("pod %d has %d leafs, which is not a multiple of the leaf group size %d", podIndex, len(leafs), size)
+		}
+		for _, n := range leafs {
+			relativeIndex, err := strconv.Atoi(n.GetRelativeNodeIndex())
+			if err != nil {
+				return err
+			}
+			n.UpdateLabel(map[string]string{
+				KeyRedundancyGroup: strconv.Itoa((relativeIndex-1)/int(size) + 1),
+			})
+		}
+	}
+	return nil
+}
+
+// NewLeafISLWirer returns a leaf-leaf Wirer, which connects every two leafs of a redundancy
+// group with islsPerPeer links. The peers of a leaf are ordered by relative index and every
+// peer has its own block of ISL interfaces.
+// leaf Index -> islInterfaceStart + peer slot * islsPerPeer + actualISLId
+// peer slot  -> index of the peer in the group without the leaf itself, counting from 0
+func NewLeafISLWirer(islsPerPeer, islInterfaceStart uint32) Wirer {
+	return WirerFunc(func(w Wiring) error {
+		if islsPerPeer == 0 {
+			return nil
+		}
+
+		// leafs per pod and redundancy group
+		groups := map[string][]Node{}
+		for _, n := range w.NodesByLabel(positionSelector(topov1alpha1.PositionLeaf, nil)) {
+			if n.GetRedundancyGroup() == "" {
+				continue
+			}
+			key := n.GetPodIndex() + "/" + n.GetRedundancyGroup()
+			groups[key] = append(groups[key], n)
+		}
+
+		for _, key := range sortedKeys(groups) {
+			members := groups[key]
+			sort.Slice(members, func(i, j int) bool {
+				ri, _ := strconv.Atoi(members[i].GetRelativeNodeIndex())
+				rj, _ := strconv.Atoi(members[j].GetRelativeNodeIndex())
+				return ri < rj
+			})
+			for i := 0; i < len(members); i++ {
+				for j := i + 1; j < len(members); j++ {
+					// j is peer slot j-1 of i, as i itself is skipped, i is peer slot i of j
+					for u := uint32(0); u < islsPerPeer; u++ {
+						w.AddISL(members[i], members[j],
+							islInterfaceStart+uint32(j-1)*islsPerPeer+u,
+							islInterfaceStart+uint32(i)*islsPerPeer+u,
+						)
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,154 @@
+package fabric
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// islNames returns the interfaces of the node on the ISLs to the peer
+func islNames(f Fabric, node, peer string) []string {
+	names := []string{}
+	for _, l := range f.GetLinks() {
+		if (l.FromNodeName() == node && l.ToNodeName() == peer) || (l.FromNodeName() == peer && l.ToNodeName() == node) {
+			if l.GetEndpointLabel(node, KeyPortType) == "" {
+				names = append(names, l.GetLabels()[node])
+			}
+		}
+	}
+	return names
+}
+
+func TestLeafGroups(t *testing.T) {
+	tests := map[string]struct {
+		leafs  int
+		config *LeafGroupConfig
+		// wantGroups are the redundancy groups of the leafs
+		wantGroups map[string]string
+		// wantISLs are the ISL interfaces of the first leaf of the pair to the second
+		wantISLs map[[2]string][]string
+		wantErr  bool
+	}{
+		"pairs": {
+			leafs:  4,
+			config: &LeafGroupConfig{Size: 2, ISLsPerPeer: 2, ISLInterfaceStart: 20},
+			wantGroups: map[string]string{
+				"pod1-leaf1": "1",
+				"pod1-leaf2": "1",
+				"pod1-leaf3": "2",
+				"pod1-leaf4": "2",
+			},
+			wantISLs: map[[2]string][]string{
+				{"pod1-leaf1", "pod1-leaf2"}: {"int-1/20", "int-1/21"},
+				{"pod1-leaf2", "pod1-leaf1"}: {"int-1/20", "int-1/21"},
+				{"pod1-leaf3", "pod1-leaf4"}: {"int-1/20", "int-1/21"},
+				{"pod1-leaf2", "pod1-leaf3"}: {},
+			},
+		},
+		"triples have a block per peer": {
+			leafs:  3,
+			config: &LeafGroupConfig{Size: 3, ISLsPerPeer: 1, ISLInterfaceStart: 20},
+			wantGroups: map[string]string{
+				"pod1-leaf1": "1",
+				"pod1-leaf3": "1",
+			},
+			wantISLs: map[[2]string][]string{
+				{"pod1-leaf1", "pod1-leaf2"}: {"int-1/20"},
+				{"pod1-leaf1", "pod1-leaf3"}: {"int-1/21"},
+				{"pod1-leaf2", "pod1-leaf1"}: {"int-1/20"},
+				{"pod1-leaf2", "pod1-leaf3"}: {"int-1/21"},
+				{"pod1-leaf3", "pod1-leaf1"}: {"int-1/20"},
+				{"pod1-leaf3", "pod1-leaf2"}: {"int-1/21"},
+			},
+		},
+		"multihoming without ISLs": {
+			leafs:      2,
+			config:     &LeafGroupConfig{Size: 2},
+			wantGroups: map[string]string{"pod1-leaf2": "1"},
+			wantISLs: map[[2]string][]string{
+				{"pod1-leaf1", "pod1-leaf2"}: {},
+			},
+		},
+		"odd leaf count": {
+			leafs:   3,
+			config:  &LeafGroupConfig{Size: 2},
+			wantErr: true,
+		},
+		"group of one": {
+			leafs:   2,
+			config:  &LeafGroupConfig{Size: 1},
+			wantErr: true,
+		},
+		"ISLs without interface start": {
+			leafs:   2,
+			config:  &LeafGroupConfig{Size: 2, ISLsPerPeer: 1},
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := newTestFabric(t, fmt.Sprintf(podTemplate, tc.leafs, 1), WithLeafGroups(tc.config))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			for node, want := range tc.wantGroups {
+				if got := getTestNode(t, f, node).GetRedundancyGroup(); got != want {
+					t.Errorf("%s redundancy group = %s, want %s", node, got, want)
+				}
+			}
+			for pair, want := range tc.wantISLs {
+				got := islNames(f, pair[0], pair[1])
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s ISLs to %s = %v, want %v", pair[0], pair[1], got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLeafGroupAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations map[string]string
+		want        *LeafGroupConfig
+		wantErr     bool
+	}{
+		"none": {
+			annotations: map[string]string{},
+		},
+		"ISLs without size": {
+			annotations: map[string]string{AnnotationLeafISLsPerPeer: "2"},
+		},
+		"size and ISLs": {
+			annotations: map[string]string{
+				AnnotationLeafGroupSize:         "2",
+				AnnotationLeafISLsPerPeer:       "2",
+				AnnotationLeafISLInterfaceStart: "49",
+			},
+			want: &LeafGroupConfig{Size: 2, ISLsPerPeer: 2, ISLInterfaceStart: 49},
+		},
+		"invalid size": {
+			annotations: map[string]string{AnnotationLeafGroupSize: "pair"},
+			wantErr:     true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts, err := leafGroupOptions(tc.annotations)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("leafGroupOptions() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			f := &fabric{wirers: map[TierPair]Wirer{}}
+			for _, o := range opts {
+				o(f)
+			}
+			if !reflect.DeepEqual(f.leafGroups, tc.want) {
+				t.Errorf("leaf groups = %+v, want %+v", f.leafGroups, tc.want)
+			}
+		})
+	}
+}
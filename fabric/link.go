@@ -10,6 +10,33 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 )
 
+const (
+	// KeyIfIndex is the interface index allocated on the endpoint
+	KeyIfIndex = "ifIndex"
+	// KeyPort is the port index of the endpoint on the node, the uplinks are offset by the platform
+	// and every sub-port of a broken out port has its own index
+	KeyPort = "port"
+	// KeyIPv4 is the IPv4 address of the endpoint
+	KeyIPv4 = "ipv4"
+	// KeyIPv6 is the IPv6 address of the endpoint
+	KeyIPv6 = "ipv6"
+	// KeyPeerASN is the ASN of the peer of the endpoint, the endpoint ASN uses KeyASN
+	KeyPeerASN = "peerAsn"
+	// KeyPortType is the port range of the platform the port of the endpoint is allocated
+	// from, PortTypeUplink or PortTypeDownlink, ISLs have no port type
+	KeyPortType = "portType"
+)
+
+const (
+	PortTypeUplink   = "uplink"
+	PortTypeDownlink = "downlink"
+)
+
+// EndpointLabelKey returns the link label key for an attribute of a link endpoint.
+func EndpointLabelKey(nodeName, key string) string {
+	return fmt.Sprintf("%s/%s", nodeName, key)
+}
+
 type Link interface {
 	From() graph.Node
 	To() graph.Node
@@ -21,6 +48,7 @@ type Link interface {
 	ToNodeName() string
 	FromIfName() string
 	ToIfName() string
+	GetEndpointLabel(nodeName, key string) string
 
 	Attributes() []encoding.Attribute
 	SetLabel(label map[string]string) error
@@ -60,6 +88,10 @@ func (l *link) ToNodeName() string   { return l.To().(Node).String() }
 func (l *link) FromIfName() string   { return l.GetLabels()[l.FromNodeName()] }
 func (l *link) ToIfName() string     { return l.GetLabels()[l.ToNodeName()] }
 
+func (l *link) GetEndpointLabel(nodeName, key string) string {
+	return l.GetLabels()[EndpointLabelKey(nodeName, key)]
+}
+
 // Attributes implements the encoding.Attributer interface.
 func (l *link) Attributes() []encoding.Attribute {
 	var keys []string
@@ -0,0 +1,168 @@
+package fabric
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+const (
+	// KindTemplate is the kind of the template resources
+	KindTemplate = "Template"
+	// KindDefinition is the kind of the definition resources
+	KindDefinition = "Definition"
+)
+
+// Resources are the template and definition resources decoded from a stream.
+type Resources struct {
+	Templates     []*topov1alpha1.Template
+	Definitions   []*topov1alpha1.Definition
+	RailTemplates []*RailTemplate
+}
+
+// ReadResources decodes all template, definition and rail template resources from a JSON or (multi-document)
+// YAML stream. Documents of another kind are skipped, so manifests can be used as is.
+// A bare fabric template without kind and spec is wrapped in a template resource.
+func ReadResources(r io.Reader) (*Resources, error) {
+	res := &Resources{
+		Templates:     make([]*topov1alpha1.Template, 0),
+		Definitions:   make([]*topov1alpha1.Definition, 0),
+		RailTemplates: make([]*RailTemplate, 0),
+	}
+
+	d := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		raw := json.RawMessage{}
+		if err := d.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+			// empty document
+			continue
+		}
+
+		tm := struct {
+			metav1.TypeMeta `json:",inline"`
+			Spec            json.RawMessage `json:"spec,omitempty"`
+		}{}
+		if err := json.Unmarshal(raw, &tm); err != nil {
+			return nil, err
+		}
+
+		switch {
+		case tm.Kind == KindTemplate || (tm.Kind == "" && tm.Spec == nil):
+			t, err := decodeTemplate(tm.Kind, raw)
+			if err != nil {
+				return nil, err
+			}
+			res.Templates = append(res.Templates, t)
+		case tm.Kind == KindDefinition:
+			def := &topov1alpha1.Definition{}
+			if err := json.Unmarshal(raw, def); err != nil {
+				return nil, err
+			}
+			res.Definitions = append(res.Definitions, def)
+		case tm.Kind == KindRailTemplate:
+			rt := &RailTemplate{}
+			if err := json.Unmarshal(raw, rt); err != nil {
+				return nil, err
+			}
+			res.RailTemplates = append(res.RailTemplates, rt)
+		}
+	}
+	return res, nil
+}
+
+// ReadTemplates decodes all template resources from a JSON or (multi-document) YAML stream.
+func ReadTemplates(r io.Reader) ([]*topov1alpha1.Template, error) {
+	res, err := ReadResources(r)
+	if err != nil {
+		return nil, err
+	}
+	return res.Templates, nil
+}
+
+// ReadTemplate decodes the template resource with the given name from a JSON or (multi-document)
+// YAML stream. An empty name selects the template if the stream has only one.
+func ReadTemplate(r io.Reader, name string) (*topov1alpha1.Template, error) {
+	templates, err := ReadTemplates(r)
+	if err != nil {
+		return nil, err
+	}
+	return SelectTemplate(templates, name)
+}
+
+// SelectTemplate returns the template with the given name.
+// An empty name selects the template if there is only one.
+func SelectTemplate(templates []*topov1alpha1.Template, name string) (*topov1alpha1.Template, error) {
+	if name == "" {
+		switch len(templates) {
+		case 0:
+			return nil, fmt.Errorf("no %s resource found", KindTemplate)
+		case 1:
+			return templates[0], nil
+		}
+		return nil, fmt.Errorf("found %d %s resources, a name is required", len(templates), KindTemplate)
+	}
+	for _, t := range templates {
+		if t.GetName() == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s not found", KindTemplate, name)
+}
+
+// SelectRailTemplate returns the rail template with the given name.
+// An empty name selects the rail template if there is only one.
+func SelectRailTemplate(templates []*RailTemplate, name string) (*RailTemplate, error) {
+	if name == "" {
+		switch len(templates) {
+		case 0:
+			return nil, fmt.Errorf("no %s resource found", KindRailTemplate)
+		case 1:
+			return templates[0], nil
+		}
+		return nil, fmt.Errorf("found %d %s resources, a name is required", len(templates), KindRailTemplate)
+	}
+	for _, t := range templates {
+		if t.GetName() == name {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s not found", KindRailTemplate, name)
+}
+
+// decodeTemplate decodes a template document, a document without kind is a bare fabric template
+func decodeTemplate(kind string, raw json.RawMessage) (*topov1alpha1.Template, error) {
+	if kind == "" {
+		// bare fabric template, e.g. example/template.json
+		var err error
+		raw, err = json.Marshal(map[string]interface{}{
+			"kind": KindTemplate,
+			"spec": map[string]interface{}{
+				"properties": map[string]json.RawMessage{
+					"fabric": raw,
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	t := &topov1alpha1.Template{}
+	if err := json.Unmarshal(raw, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,188 @@
+package fabric
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const mixedResources = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+data:
+  key: value
+---
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: pod
+  namespace: default
+spec:
+  properties:
+    fabric:
+      pod:
+      - num: 1
+---
+---
+apiVersion: topo.yndd.io/v1alpha1
+kind: Definition
+metadata:
+  name: dc1
+  namespace: default
+spec:
+  properties:
+    templates:
+    - namespacedName: default/pod
+---
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: fabric
+spec:
+  properties:
+    fabric:
+      pod:
+      - num: 2
+`
+
+func TestReadResources(t *testing.T) {
+	example, err := os.ReadFile("../example/template.json")
+	if err != nil {
+		t.Fatalf("cannot read example template: %s", err)
+	}
+	tests := map[string]struct {
+		resources       string
+		wantTemplates   []string
+		wantDefinitions []string
+		wantRails       []string
+		wantErr         bool
+	}{
+		"multi-document yaml with mixed kinds": {
+			resources:       mixedResources,
+			wantTemplates:   []string{"pod", "fabric"},
+			wantDefinitions: []string{"dc1"},
+		},
+		"json resource": {
+			resources:     `{"apiVersion": "topo.yndd.io/v1alpha1", "kind": "Template", "metadata": {"name": "pod"}, "spec": {}}`,
+			wantTemplates: []string{"pod"},
+		},
+		"bare json fabric template": {
+			resources:     string(example),
+			wantTemplates: []string{""},
+		},
+		"rail template": {
+			resources: railTemplate,
+			wantRails: []string{"rail"},
+		},
+		"empty": {
+			resources: "---\n",
+		},
+		"invalid yaml": {
+			resources: "kind: Template\nspec: [",
+			wantErr:   true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			res, err := ReadResources(strings.NewReader(tc.resources))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ReadResources() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			templates := []string{}
+			for _, tmpl := range res.Templates {
+				templates = append(templates, tmpl.GetName())
+			}
+			definitions := []string{}
+			for _, d := range res.Definitions {
+				definitions = append(definitions, d.GetName())
+			}
+			rails := []string{}
+			for _, rt := range res.RailTemplates {
+				rails = append(rails, rt.GetName())
+			}
+			for kind, got := range map[string][2][]string{
+				KindTemplate:     {templates, tc.wantTemplates},
+				KindDefinition:   {definitions, tc.wantDefinitions},
+				KindRailTemplate: {rails, tc.wantRails},
+			} {
+				if strings.Join(got[0], ",") != strings.Join(got[1], ",") {
+					t.Errorf("%s resources = %v, want %v", kind, got[0], got[1])
+				}
+			}
+		})
+	}
+}
+
+func TestReadBareTemplate(t *testing.T) {
+	b, err := os.ReadFile("../example/template.json")
+	if err != nil {
+		t.Fatalf("cannot read example template: %s", err)
+	}
+	tmpl, err := ReadTemplate(strings.NewReader(string(b)), "")
+	if err != nil {
+		t.Fatalf("ReadTemplate() error = %v", err)
+	}
+	if tmpl.Kind != KindTemplate || tmpl.Spec.Properties == nil || tmpl.Spec.Properties.Fabric == nil ||
+		len(tmpl.Spec.Properties.Fabric.Pod) != 1 {
+		t.Errorf("bare template is not wrapped in a template resource: %+v", tmpl)
+	}
+}
+
+func TestSelectTemplate(t *testing.T) {
+	newTemplate := func(name string) *topov1alpha1.Template {
+		return &topov1alpha1.Template{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	tests := map[string]struct {
+		templates []*topov1alpha1.Template
+		name      string
+		want      string
+		wantErr   string
+	}{
+		"only template": {
+			templates: []*topov1alpha1.Template{newTemplate("pod")},
+			want:      "pod",
+		},
+		"by name": {
+			templates: []*topov1alpha1.Template{newTemplate("pod"), newTemplate("fabric")},
+			name:      "fabric",
+			want:      "fabric",
+		},
+		"no templates": {
+			wantErr: "no Template resource found",
+		},
+		"ambiguous": {
+			templates: []*topov1alpha1.Template{newTemplate("pod"), newTemplate("fabric")},
+			wantErr:   "found 2 Template resources, a name is required",
+		},
+		"missing": {
+			templates: []*topov1alpha1.Template{newTemplate("pod")},
+			name:      "fabric",
+			wantErr:   "Template fabric not found",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := SelectTemplate(tc.templates, tc.name)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("SelectTemplate() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SelectTemplate() error = %v", err)
+			}
+			if got.GetName() != tc.want {
+				t.Errorf("SelectTemplate() = %s, want %s", got.GetName(), tc.want)
+			}
+		})
+	}
+}
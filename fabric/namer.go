@@ -0,0 +1,131 @@
+package fabric
+
+import (
+	"fmt"
+	"sync"
+
+	targetv1 "github.com/yndd/target/apis/target/v1"
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+// InterfaceNamer names the interfaces of the nodes of a vendor type.
+type InterfaceNamer interface {
+	// GetInterfaceName returns the interface name of a port, sub is the sub-port
+	// of a broken out port or 0 for a port that is not broken out.
+	GetInterfaceName(port, sub uint32) string
+}
+
+// The InterfaceNamerFunc type is an adapter to allow the use of ordinary functions
+// as InterfaceNamer.
+type InterfaceNamerFunc func(port, sub uint32) string
+
+// GetInterfaceName calls f(port, sub).
+func (f InterfaceNamerFunc) GetInterfaceName(port, sub uint32) string { return f(port, sub) }
+
+// FormatInterfaceNamer names the interfaces with printf formats.
+type FormatInterfaceNamer struct {
+	// Format is the format of the interface name with the port number, e.g. int-1/%d
+	Format string
+	// BreakoutFormat is the format of the interface name with the port and sub-port number,
+	// default the format followed by /%d
+	BreakoutFormat string
+}
+
+// GetInterfaceName returns the interface name of the port or sub-port.
+func (n *FormatInterfaceNamer) GetInterfaceName(port, sub uint32) string {
+	format := n.Format
+	if format == "" {
+		format = defaultInterfaceFormat
+	}
+	if sub == 0 {
+		return fmt.Sprintf(format, port)
+	}
+	if n.BreakoutFormat != "" {
+		return fmt.Sprintf(n.BreakoutFormat, port, sub)
+	}
+	return fmt.Sprintf(format+"/%d", port, sub)
+}
+
+var (
+	// SRLInterfaceNamer names the interfaces of SR Linux, e.g. int-1/5 and int-1/5/1
+	SRLInterfaceNamer InterfaceNamer = &FormatInterfaceNamer{Format: "int-1/%d", BreakoutFormat: "int-1/%d/%d"}
+	// SROSInterfaceNamer names the connector ports of SR OS, e.g. 1/1/c5/1 and 1/1/c5/2
+	SROSInterfaceNamer InterfaceNamer = &FormatInterfaceNamer{Format: "1/1/c%d/1", BreakoutFormat: "1/1/c%d/%d"}
+)
+
+type namerKey struct {
+	vendorType targetv1.VendorType
+	platform   string
+}
+
+// defaultNamers are the namers of the vendor types for the platforms which are not in the catalog
+var defaultNamers = map[targetv1.VendorType]InterfaceNamer{
+	targetv1.VendorTypeNokiaSRL:  SRLInterfaceNamer,
+	targetv1.VendorTypeNokiaSROS: SROSInterfaceNamer,
+}
+
+var (
+	namersMu sync.RWMutex
+	namers   = map[namerKey]InterfaceNamer{}
+)
+
+// RegisterInterfaceNamer registers the InterfaceNamer of the nodes of a vendor type and
+// platform for every fabric, an empty platform registers the namer for all platforms of
+// the vendor type, including the platforms in the catalog. A registered namer replaces
+// the namer of the same vendor type and platform.
+func RegisterInterfaceNamer(vendorType targetv1.VendorType, platform string, n InterfaceNamer) {
+	namersMu.Lock()
+	defer namersMu.Unlock()
+	namers[namerKey{vendorType: vendorType, platform: platform}] = n
+}
+
+func getRegisteredInterfaceNamer(k namerKey) (InterfaceNamer, bool) {
+	namersMu.RLock()
+	defer namersMu.RUnlock()
+	n, ok := namers[k]
+	return n, ok
+}
+
+// WithInterfaceNamer specifies the InterfaceNamer of the nodes of a vendor type and platform
+// of the Fabric, an empty platform matches all platforms of the vendor type. It takes
+// precedence over the registered namers.
+func WithInterfaceNamer(vendorType targetv1.VendorType, platform string, n InterfaceNamer) Option {
+	return func(f Fabric) {
+		f.SetInterfaceNamer(vendorType, platform, n)
+	}
+}
+
+func (f *fabric) SetInterfaceNamer(vendorType targetv1.VendorType, platform string, n InterfaceNamer) {
+	if f.namers == nil {
+		f.namers = map[namerKey]InterfaceNamer{}
+	}
+	f.namers[namerKey{vendorType: vendorType, platform: platform}] = n
+}
+
+// getInterfaceNamer returns the InterfaceNamer of the nodes of the vendor info, in order of precedence:
+// - the namer of the vendor type and platform, of the fabric or registered
+// - the namer of the vendor type, of the fabric or registered
+// - the interface formats of the platform in the catalog
+// - the default namer of the vendor type
+// - the default interface format
+func (f *fabric) getInterfaceNamer(vi *topov1alpha1.FabricTierVendorInfo, p *Platform) InterfaceNamer {
+	lookup := func(k namerKey) (InterfaceNamer, bool) {
+		if n, ok := f.namers[k]; ok {
+			return n, true
+		}
+		return getRegisteredInterfaceNamer(k)
+	}
+	if n, ok := lookup(namerKey{vendorType: vi.VendorType, platform: vi.Platform}); ok {
+		return n
+	}
+	if n, ok := lookup(namerKey{vendorType: vi.VendorType}); ok {
+		return n
+	}
+	if p != nil && p.InterfaceFormat != "" {
+		return p.getInterfaceNamer()
+	}
+	if n, ok := defaultNamers[vi.VendorType]; ok {
+		return n
+	}
+	return &FormatInterfaceNamer{Format: defaultInterfaceFormat}
+}
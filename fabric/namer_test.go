@@ -0,0 +1,137 @@
+package fabric
+
+import (
+	"fmt"
+	"testing"
+
+	targetv1 "github.com/yndd/target/apis/target/v1"
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+func TestGetInterfaceNamer(t *testing.T) {
+	ethernet := &FormatInterfaceNamer{Format: "ethernet-1/%d"}
+	port := &FormatInterfaceNamer{Format: "port-%d"}
+	tests := map[string]struct {
+		vendorType targetv1.VendorType
+		platform   string
+		opts       []Option
+		registered map[namerKey]InterfaceNamer
+		want       string
+	}{
+		"catalog": {
+			vendorType: targetv1.VendorTypeNokiaSROS,
+			platform:   "SR-1",
+			want:       "1/1/c5/1",
+		},
+		"default namer of the vendor type": {
+			vendorType: targetv1.VendorTypeNokiaSROS,
+			platform:   "7750-SR-7",
+			want:       "1/1/c5/1",
+		},
+		"default interface format": {
+			vendorType: "unknown",
+			platform:   "unknown",
+			want:       "int-1/5",
+		},
+		"vendor type namer takes precedence over the catalog": {
+			vendorType: targetv1.VendorTypeNokiaSRL,
+			platform:   "IXR-D3",
+			opts:       []Option{WithInterfaceNamer(targetv1.VendorTypeNokiaSRL, "", ethernet)},
+			want:       "ethernet-1/5",
+		},
+		"registered vendor type namer takes precedence over the catalog": {
+			vendorType: targetv1.VendorTypeNokiaSRL,
+			platform:   "IXR-D3",
+			registered: map[namerKey]InterfaceNamer{{vendorType: targetv1.VendorTypeNokiaSRL}: ethernet},
+			want:       "ethernet-1/5",
+		},
+		"platform namer takes precedence over the vendor type namer": {
+			vendorType: targetv1.VendorTypeNokiaSRL,
+			platform:   "IXR-D3",
+			opts: []Option{
+				WithInterfaceNamer(targetv1.VendorTypeNokiaSRL, "", ethernet),
+				WithInterfaceNamer(targetv1.VendorTypeNokiaSRL, "IXR-D3", port),
+			},
+			want: "port-5",
+		},
+		"fabric namer takes precedence over the registered namer": {
+			vendorType: targetv1.VendorTypeNokiaSRL,
+			platform:   "IXR-D3",
+			opts:       []Option{WithInterfaceNamer(targetv1.VendorTypeNokiaSRL, "IXR-D3", port)},
+			registered: map[namerKey]InterfaceNamer{{vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D3"}: ethernet},
+			want:       "port-5",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			for k, n := range tc.registered {
+				RegisterInterfaceNamer(k.vendorType, k.platform, n)
+				k := k
+				t.Cleanup(func() {
+					namersMu.Lock()
+					defer namersMu.Unlock()
+					delete(namers, k)
+				})
+			}
+			f := &fabric{catalog: DefaultPlatformCatalog()}
+			for _, o := range tc.opts {
+				o(f)
+			}
+			vi := &topov1alpha1.FabricTierVendorInfo{VendorType: tc.vendorType, Platform: tc.platform}
+			p, _ := f.catalog.Get(tc.vendorType, tc.platform)
+			if got := f.getInterfaceNamer(vi, p).GetInterfaceName(5, 0); got != tc.want {
+				t.Errorf("interface name = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatInterfaceNamer(t *testing.T) {
+	tests := map[string]struct {
+		namer InterfaceNamer
+		port  uint32
+		sub   uint32
+		want  string
+	}{
+		"srl port":                {namer: SRLInterfaceNamer, port: 5, want: "int-1/5"},
+		"srl sub-port":            {namer: SRLInterfaceNamer, port: 5, sub: 1, want: "int-1/5/1"},
+		"sros connector":          {namer: SROSInterfaceNamer, port: 5, want: "1/1/c5/1"},
+		"sros connector sub-port": {namer: SROSInterfaceNamer, port: 5, sub: 2, want: "1/1/c5/2"},
+		"default format":          {namer: &FormatInterfaceNamer{}, port: 3, want: "int-1/3"},
+		"default breakout format": {namer: &FormatInterfaceNamer{Format: "eth%d"}, port: 3, sub: 4, want: "eth3/4"},
+		"function":                {namer: InterfaceNamerFunc(func(port, sub uint32) string { return fmt.Sprintf("et-%d", port) }), port: 7, want: "et-7"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.namer.GetInterfaceName(tc.port, tc.sub); got != tc.want {
+				t.Errorf("GetInterfaceName(%d, %d) = %s, want %s", tc.port, tc.sub, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSROSInterfaceNames(t *testing.T) {
+	tests := map[string]struct {
+		opts []Option
+		link string
+	}{
+		"connector": {
+			link: "plane1-superspine1:1/1/c1/1--pod1-spine1:int-1/25",
+		},
+		"broken out connector": {
+			opts: []Option{WithBreakouts(&PortBreakout{Position: "superspine", Ports: PortRange{Start: 1, End: 1}, Lanes: 4})},
+			link: "plane1-superspine1:1/1/c1/2--pod1-spine1:int-1/26",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := mustNewTestFabric(t, srosTemplate, tc.opts...)
+			for _, l := range f.GetLinks() {
+				if linkKey(l) == tc.link {
+					return
+				}
+			}
+			t.Errorf("link %s not found", tc.link)
+		})
+	}
+}
@@ -17,6 +17,15 @@ const (
 	KeyPodIndex          = "podIndex"
 	KeyPlaneIndex        = "planeIndex"
 	KeyRelativeNodeIndex = "relativeNodeIndex"
+	KeySystemIPv4        = "systemIPv4"
+	KeySystemIPv6        = "systemIPv6"
+	KeyRouterID          = "routerID"
+	KeyASN               = "asn"
+	KeyRedundancyGroup   = "redundancyGroup"
+	KeyRackIndex         = "rackIndex"
+	KeyRail              = "rail"
+	// KeyName overrides the name derived from the position and indexes
+	KeyName = "name"
 )
 
 type Node interface {
@@ -29,11 +38,24 @@ type Node interface {
 	GetPodIndex() string
 	GetVendorType() targetv1.VendorType
 	GetPlatform() string
+	GetPlatformInfo() *Platform
 	GetUplinkPerNode() uint32
 	GetInterfaceName(idx uint32) string
 	GetInterfaceNameWithPlatfromOffset(idx uint32) string
+	GetUplinkPort(idx uint32) uint32
+	GetPortCount() uint32
+	GetPlatformPort(idx uint32) uint32
+	GetPlatformSubPort(idx uint32) uint32
+	GetInterfaceSpeed(idx uint32) string
 	IsToBeDeployed() bool
 	GetLocation() *topov1alpha1.Location
+	GetSystemIPv4() string
+	GetSystemIPv6() string
+	GetRouterID() string
+	GetASN() string
+	GetRedundancyGroup() string
+	GetRackIndex() string
+	GetRail() string
 
 	Attributes() []encoding.Attribute
 	SetLabel(label map[string]string) error
@@ -46,9 +68,13 @@ type nodeInfo struct {
 	position          topov1alpha1.Position // tier1, tier2, tier3
 	podIndex          uint32                // used for leaf and spines
 	planeIndex        uint32                // used for superspines
-	relativeNodeIndex uint32                // relative index for the position within the pod (leaf/spine) or plane (superspine)
+	relativeNodeIndex uint32                // relative index for the position within the pod (leaf/spine), plane (superspine) or rack (server)
+	rackIndex         uint32                // used for servers
 	uplinkPerNode     uint32
 	vendorInfo        *topov1alpha1.FabricTierVendorInfo
+	platform          *Platform // nil if the platform is not in the catalog
+	namer             InterfaceNamer
+	breakouts         []*PortBreakout
 	toBeDeployed      bool
 	location          *topov1alpha1.Location
 }
@@ -58,31 +84,46 @@ func NewNode(nodeInfo *nodeInfo) (Node, error) {
 		graphIndex: nodeInfo.graphIndex,
 		//relativeNodeIndex: nodeInfo.relativeNodeIndex,
 		vendorInfo:   nodeInfo.vendorInfo,
+		platform:     nodeInfo.platform,
+		namer:        nodeInfo.namer,
+		slots:        getPortSlots(nodeInfo.platform, nodeInfo.breakouts),
 		toBeDeployed: nodeInfo.toBeDeployed,
 		location:     nodeInfo.location,
 	}
 
-	labels := map[string]string{
-		KeyPosition:          string(nodeInfo.position),
-		KeyRelativeNodeIndex: strconv.Itoa(int(nodeInfo.relativeNodeIndex)),
-	}
-
 	switch nodeInfo.position {
-	case topov1alpha1.PositionLeaf, topov1alpha1.PositionSpine:
-		//n.podIndex = nodeInfo.podIndex
+	case topov1alpha1.PositionLeaf, topov1alpha1.PositionSpine, topov1alpha1.PositionBorderLeaf:
 		n.uplinkPerNode = nodeInfo.uplinkPerNode
-		labels[KeyPodIndex] = strconv.Itoa(int(nodeInfo.podIndex))
-	case topov1alpha1.PositionSuperspine:
-		//n.planeIndex = nodeInfo.planeIndex
-		labels[KeyPlaneIndex] = strconv.Itoa(int(nodeInfo.planeIndex))
 	}
-	if err := n.SetLabel(labels); err != nil {
+	if err := n.SetLabel(nodeInfo.getLabels()); err != nil {
 		return nil, err
 	}
 	return n, nil
 
 }
 
+// getLabels returns the position and index labels of the node
+func (ni *nodeInfo) getLabels() map[string]string {
+	labels := map[string]string{
+		KeyPosition:          string(ni.position),
+		KeyRelativeNodeIndex: strconv.Itoa(int(ni.relativeNodeIndex)),
+	}
+
+	switch ni.position {
+	case topov1alpha1.PositionLeaf, topov1alpha1.PositionSpine:
+		labels[KeyPodIndex] = strconv.Itoa(int(ni.podIndex))
+	case topov1alpha1.PositionSuperspine:
+		labels[KeyPlaneIndex] = strconv.Itoa(int(ni.planeIndex))
+	case PositionServer:
+		labels[KeyPodIndex] = strconv.Itoa(int(ni.podIndex))
+		labels[KeyRackIndex] = strconv.Itoa(int(ni.rackIndex))
+	}
+	return labels
+}
+
+// getName returns the name of the node derived from the position and indexes
+func (ni *nodeInfo) getName() string { return getNodeName(ni.getLabels()) }
+
 type node struct {
 	//log      logging.Logger
 	//position   topov1alpha1.Position
@@ -96,6 +137,9 @@ type node struct {
 	//planeIndex    uint32
 	attrs         labels.Set
 	vendorInfo    *topov1alpha1.FabricTierVendorInfo
+	platform      *Platform
+	namer         InterfaceNamer
+	slots         []portSlot
 	uplinkPerNode uint32
 	toBeDeployed  bool
 	location      *topov1alpha1.Location
@@ -110,57 +154,142 @@ func (n *node) GetPlaneIndex() string               { return n.GetLabels()[KeyPl
 func (n *node) GetPodIndex() string                 { return n.GetLabels()[KeyPodIndex] }
 func (n *node) GetVendorType() targetv1.VendorType  { return n.vendorInfo.VendorType }
 func (n *node) GetPlatform() string                 { return n.vendorInfo.Platform }
+func (n *node) GetPlatformInfo() *Platform          { return n.platform }
 func (n *node) GetUplinkPerNode() uint32            { return n.uplinkPerNode }
 func (n *node) IsToBeDeployed() bool                { return n.toBeDeployed }
 func (n *node) GetLocation() *topov1alpha1.Location { return n.location }
+func (n *node) GetSystemIPv4() string               { return n.GetLabels()[KeySystemIPv4] }
+func (n *node) GetSystemIPv6() string               { return n.GetLabels()[KeySystemIPv6] }
+func (n *node) GetRouterID() string                 { return n.GetLabels()[KeyRouterID] }
+func (n *node) GetASN() string                      { return n.GetLabels()[KeyASN] }
+func (n *node) GetRedundancyGroup() string          { return n.GetLabels()[KeyRedundancyGroup] }
+func (n *node) GetRackIndex() string                { return n.GetLabels()[KeyRackIndex] }
+func (n *node) GetRail() string                     { return n.GetLabels()[KeyRail] }
 
+// GetInterfaceName returns the interface name of port idx, named by the InterfaceNamer
+// of the vendor type and platform of the node, empty if idx is 0
 func (n *node) GetInterfaceName(idx uint32) string {
-	return fmt.Sprintf("int-1/%d", idx)
+	if n.GetPosition() == string(PositionServer) {
+		return fmt.Sprintf("eth%d", idx)
+	}
+	namer := n.namer
+	if namer == nil {
+		namer = &FormatInterfaceNamer{Format: defaultInterfaceFormat}
+	}
+	if n.platform == nil {
+		return namer.GetInterfaceName(idx, 0)
+	}
+	if idx < 1 {
+		// interface indexes start at 1
+		return ""
+	}
+	if idx > uint32(len(n.slots)) {
+		// beyond the ports of the platform, the port validation reports it
+		return namer.GetInterfaceName(n.platform.Ports+idx-uint32(len(n.slots)), 0)
+	}
+	slot := n.slots[idx-1]
+	return namer.GetInterfaceName(slot.port, slot.sub)
 }
 
-func (n *node) GetInterfaceNameWithPlatfromOffset(idx uint32) string {
+// GetPortCount returns the number of interface indexes of the node, a broken out
+// port has an index per sub-port, 0 if the platform is not in the catalog
+func (n *node) GetPortCount() uint32 { return uint32(len(n.slots)) }
 
-	var actualIndex uint32
-	switch n.GetVendorType() {
-	case targetv1.VendorTypeNokiaSRL:
-		switch n.GetPosition() {
-		case string(topov1alpha1.PositionLeaf):
-			switch n.GetPlatform() {
-			case "IXR-D3":
-				actualIndex = idx + 26
-			case "IXR-D2":
-				actualIndex = idx + 48
-			}
-		case string(topov1alpha1.PositionSpine):
-			switch n.GetPlatform() {
-			case "IXR-D3":
-				actualIndex = idx + 24
-			}
+// GetPlatformPort returns the port of the platform of port idx, a sub-port returns the
+// port it is broken out of, 0 if the platform is not in the catalog or has no port idx
+func (n *node) GetPlatformPort(idx uint32) uint32 {
+	if idx < 1 || idx > uint32(len(n.slots)) {
+		return 0
+	}
+	return n.slots[idx-1].port
+}
+
+// GetPlatformSubPort returns the sub-port of port idx, 0 if the port is not broken out
+func (n *node) GetPlatformSubPort(idx uint32) uint32 {
+	if idx < 1 || idx > uint32(len(n.slots)) {
+		return 0
+	}
+	return n.slots[idx-1].sub
+}
+
+// GetInterfaceSpeed returns the speed of port idx, a sub-port has the speed of the breakout,
+// empty if unknown
+func (n *node) GetInterfaceSpeed(idx uint32) string {
+	if idx < 1 || idx > uint32(len(n.slots)) {
+		return ""
+	}
+	slot := n.slots[idx-1]
+	if slot.sub == 0 {
+		return n.platform.GetSpeed(slot.port)
+	}
+	lanes := uint32(0)
+	for _, s := range n.slots {
+		if s.port == slot.port {
+			lanes++
 		}
-	case targetv1.VendorTypeNokiaSROS:
 	}
+	return n.platform.GetBreakoutSpeed(slot.port, lanes)
+}
 
-	return fmt.Sprintf("int-1/%d", actualIndex)
+// GetInterfaceNameWithPlatfromOffset returns the interface name of uplink idx, the uplinks
+// of the position start at the first uplink port of the platform in the catalog. Platforms
+// which are not in the catalog have no offset.
+func (n *node) GetInterfaceNameWithPlatfromOffset(idx uint32) string {
+	return n.GetInterfaceName(n.GetUplinkPort(idx))
 }
 
-func (n *node) getName() string {
-	switch n.GetPosition() {
+// GetUplinkPort returns the port of uplink idx
+func (n *node) GetUplinkPort(idx uint32) uint32 {
+	// servers have no uplink ports, the NICs are used as is
+	if n.GetPosition() == string(PositionServer) || n.platform == nil {
+		return idx
+	}
+	// superspines use the uplink ports for the borderleafs
+	uplinks := n.platform.GetUplinks(n.GetPosition())
+	if uplinks == nil {
+		return idx
+	}
+	// the uplinks start at the first sub-port of the first uplink port
+	for i, slot := range n.slots {
+		if slot.port == uplinks.Start {
+			return uint32(i) + idx
+		}
+	}
+	return uplinks.Start - 1 + idx
+}
+
+func (n *node) getName() string { return getNodeName(n.GetLabels()) }
+
+// getNodeName returns the name of the node with the labels, KeyName overrides the name
+// derived from the position and indexes
+func getNodeName(l labels.Set) string {
+	if name, ok := l[KeyName]; ok {
+		return name
+	}
+	switch l[KeyPosition] {
 	case string(topov1alpha1.PositionSuperspine):
 		return fmt.Sprintf("plane%s-%s%s",
-			n.GetLabels()[KeyPlaneIndex],
-			n.GetLabels()[KeyPosition],
-			n.GetLabels()[KeyRelativeNodeIndex],
+			l[KeyPlaneIndex],
+			l[KeyPosition],
+			l[KeyRelativeNodeIndex],
 		)
 	case string(topov1alpha1.PositionBorderLeaf):
 		return fmt.Sprintf("%s%s",
-			n.GetLabels()[KeyPosition],
-			n.GetLabels()[KeyRelativeNodeIndex],
+			l[KeyPosition],
+			l[KeyRelativeNodeIndex],
+		)
+	case string(PositionServer):
+		return fmt.Sprintf("pod%s-rack%s-%s%s",
+			l[KeyPodIndex],
+			l[KeyRackIndex],
+			l[KeyPosition],
+			l[KeyRelativeNodeIndex],
 		)
 	case string(topov1alpha1.PositionSpine), string(topov1alpha1.PositionLeaf):
 		return fmt.Sprintf("pod%s-%s%s",
-			n.GetLabels()[KeyPodIndex],
-			n.GetLabels()[KeyPosition],
-			n.GetLabels()[KeyRelativeNodeIndex],
+			l[KeyPodIndex],
+			l[KeyPosition],
+			l[KeyRelativeNodeIndex],
 		)
 	}
 	return "dummy"
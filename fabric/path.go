@@ -0,0 +1,201 @@
+package fabric
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/iterator"
+	"gonum.org/v1/gonum/graph/path"
+)
+
+// PathReport are the equal-cost shortest paths between two nodes. Servers don't forward
+// traffic, so they are only used as the source or destination of a path.
+type PathReport struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Hops is the number of links of every shortest path, 0 if there is no path
+	Hops int `json:"hops"`
+	// Paths is the number of equal-cost link paths, every parallel link is a path of its own
+	Paths int `json:"paths"`
+	// NodePaths are the shortest paths by node, ordered by node names
+	NodePaths []*NodePath `json:"nodePaths"`
+}
+
+// NodePath is a shortest path by node with the parallel links of every hop.
+type NodePath struct {
+	Nodes []string `json:"nodes"`
+	// Links are the parallel links of every hop, hop i connects node i and i+1
+	Links [][]string `json:"links"`
+	// Paths is the number of link paths, the product of the parallel links of the hops
+	Paths int `json:"paths"`
+}
+
+// LinkSequences returns every link path of the node path.
+func (p *NodePath) LinkSequences() [][]string {
+	seqs := [][]string{{}}
+	for _, links := range p.Links {
+		next := make([][]string, 0, len(seqs)*len(links))
+		for _, seq := range seqs {
+			for _, l := range links {
+				s := make([]string, len(seq), len(seq)+1)
+				copy(s, seq)
+				next = append(next, append(s, l))
+			}
+		}
+		seqs = next
+	}
+	return seqs
+}
+
+// GetPaths returns the equal-cost shortest paths between the nodes with the names from and to.
+func (f *fabric) GetPaths(from, to string) (*PathReport, error) {
+	src, ok := f.getNode(from)
+	if !ok {
+		return nil, fmt.Errorf("unknown node %s", from)
+	}
+	dst, ok := f.getNode(to)
+	if !ok {
+		return nil, fmt.Errorf("unknown node %s", to)
+	}
+	return f.newPathGraph().paths(src, dst), nil
+}
+
+// GetLeafPaths returns the equal-cost shortest paths between every pair of leafs, ordered by node names.
+func (f *fabric) GetLeafPaths() []*PathReport {
+	leafs := f.nodesByLabel(positionSelector(topov1alpha1.PositionLeaf, nil))
+	sort.Slice(leafs, func(i, j int) bool { return leafs[i].String() < leafs[j].String() })
+
+	return f.newPathGraph().leafPaths(leafs)
+}
+
+// getNode returns the node with the name
+func (f *fabric) getNode(name string) (Node, bool) {
+	for _, n := range f.GetNodes() {
+		if n.String() == name {
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// pathGraph is the view of the fabric graph used by the path analysis, without the failed
+// nodes and links
+type pathGraph struct {
+	f *fabric
+	// from and to are the source and destination of the paths, which can be servers
+	from        int64
+	to          int64
+	failedNodes map[int64]bool
+	failedLinks map[string]bool
+}
+
+func (f *fabric) newPathGraph() *pathGraph {
+	return &pathGraph{f: f, failedNodes: map[int64]bool{}, failedLinks: map[string]bool{}}
+}
+
+// transit returns true if paths can go through the node
+func (g *pathGraph) transit(n Node) bool {
+	if g.failedNodes[n.ID()] {
+		return false
+	}
+	if n.ID() == g.from || n.ID() == g.to {
+		return true
+	}
+	return !isServer(n)
+}
+
+// From implements the traverse.Graph interface.
+func (g *pathGraph) From(id int64) graph.Nodes {
+	if n, ok := g.f.graph.Node(id).(Node); !ok || !g.transit(n) {
+		return graph.Empty
+	}
+	nodes := []graph.Node{}
+	it := g.f.graph.From(id)
+	for it.Next() {
+		n := it.Node().(Node)
+		if g.transit(n) && len(g.lines(id, n.ID())) > 0 {
+			nodes = append(nodes, n)
+		}
+	}
+	return iterator.NewOrderedNodes(nodes)
+}
+
+// Edge implements the traverse.Graph interface.
+func (g *pathGraph) Edge(uid, vid int64) graph.Edge {
+	if len(g.lines(uid, vid)) == 0 {
+		return nil
+	}
+	return g.f.graph.Edge(uid, vid)
+}
+
+// lines returns the names of the parallel links between two nodes which did not fail, ordered by name
+func (g *pathGraph) lines(uid, vid int64) []string {
+	links := []string{}
+	it := g.f.graph.Lines(uid, vid)
+	if it == nil {
+		return links
+	}
+	for it.Next() {
+		if key := linkKey(it.Line().(Link)); !g.failedLinks[key] {
+			links = append(links, key)
+		}
+	}
+	sort.Strings(links)
+	return links
+}
+
+// paths returns the equal-cost shortest paths between src and dst
+func (g *pathGraph) paths(src, dst Node) *PathReport {
+	g.from, g.to = src.ID(), dst.ID()
+	return g.report(path.DijkstraAllFrom(src, g), src, dst)
+}
+
+// leafPaths returns the shortest paths between every pair of the leafs
+func (g *pathGraph) leafPaths(leafs []Node) []*PathReport {
+	reports := []*PathReport{}
+	for i, src := range leafs {
+		// the shortest paths from a leaf are computed once for all other leafs
+		g.from, g.to = src.ID(), src.ID()
+		sp := path.DijkstraAllFrom(src, g)
+		for _, dst := range leafs[i+1:] {
+			g.to = dst.ID()
+			reports = append(reports, g.report(sp, src, dst))
+		}
+	}
+	return reports
+}
+
+// report returns the path report of the shortest paths from src to dst
+func (g *pathGraph) report(sp path.ShortestAlts, src, dst Node) *PathReport {
+	r := &PathReport{
+		From:      src.String(),
+		To:        dst.String(),
+		NodePaths: []*NodePath{},
+	}
+	if src.ID() == dst.ID() {
+		return r
+	}
+	nodePaths, _ := sp.AllTo(dst.ID())
+	for _, nodes := range nodePaths {
+		np := &NodePath{Paths: 1}
+		for i, n := range nodes {
+			np.Nodes = append(np.Nodes, n.(Node).String())
+			if i == 0 {
+				continue
+			}
+			links := g.lines(nodes[i-1].ID(), n.ID())
+			np.Links = append(np.Links, links)
+			np.Paths *= len(links)
+		}
+		r.Hops = len(np.Links)
+		r.Paths += np.Paths
+		r.NodePaths = append(r.NodePaths, np)
+	}
+	sort.Slice(r.NodePaths, func(i, j int) bool {
+		return strings.Join(r.NodePaths[i].Nodes, " ") < strings.Join(r.NodePaths[j].Nodes, " ")
+	})
+	return r
+}
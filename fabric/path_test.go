@@ -0,0 +1,83 @@
+package fabric
+
+import (
+	"testing"
+)
+
+func TestGetPaths(t *testing.T) {
+	// the example has 2 spines per pod and 2 superspines per plane, the spines and
+	// leafs have 2 uplinks per node
+	tests := map[string]struct {
+		from, to  string
+		hops      int
+		paths     int
+		nodePaths int
+		wantErr   bool
+	}{
+		"leafs of a pod": {
+			from: "pod1-leaf1", to: "pod1-leaf2",
+			hops: 2, paths: 2 * (2 * 2), nodePaths: 2,
+		},
+		"leafs of different pods": {
+			from: "pod1-leaf1", to: "pod2-leaf4",
+			hops: 4, paths: 2 * 2 * (2 * 2 * 2 * 2), nodePaths: 4,
+		},
+		"leaf to superspine": {
+			from: "pod1-leaf1", to: "plane2-superspine1",
+			hops: 2, paths: 2 * 2, nodePaths: 1,
+		},
+		"same node": {
+			from: "pod1-leaf1", to: "pod1-leaf1",
+		},
+		"unknown node": {
+			from: "pod1-leaf1", to: "pod3-leaf1",
+			wantErr: true,
+		},
+	}
+	f := mustNewTestFabric(t, exampleTemplate(t))
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r, err := f.GetPaths(tc.from, tc.to)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetPaths() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if r.Hops != tc.hops || r.Paths != tc.paths || len(r.NodePaths) != tc.nodePaths {
+				t.Errorf("hops, paths and node paths = %d, %d, %d, want %d, %d, %d",
+					r.Hops, r.Paths, len(r.NodePaths), tc.hops, tc.paths, tc.nodePaths)
+			}
+			total := 0
+			for _, np := range r.NodePaths {
+				if len(np.Nodes) != r.Hops+1 || np.Nodes[0] != tc.from || np.Nodes[len(np.Nodes)-1] != tc.to {
+					t.Errorf("node path %v does not connect %s and %s in %d hops", np.Nodes, tc.from, tc.to, r.Hops)
+				}
+				if got := len(np.LinkSequences()); got != np.Paths {
+					t.Errorf("node path %v has %d link sequences, want %d", np.Nodes, got, np.Paths)
+				}
+				total += np.Paths
+			}
+			if total != r.Paths {
+				t.Errorf("node paths have %d paths, want %d", total, r.Paths)
+			}
+		})
+	}
+}
+
+func TestGetLeafPaths(t *testing.T) {
+	f := mustNewTestFabric(t, exampleTemplate(t))
+	reports := f.GetLeafPaths()
+	// 8 leafs -> 8 * 7 / 2 pairs
+	if len(reports) != 28 {
+		t.Fatalf("leaf pairs = %d, want 28", len(reports))
+	}
+	for _, r := range reports {
+		if r.From >= r.To {
+			t.Errorf("leaf pair %s %s is not ordered by name", r.From, r.To)
+		}
+		if r.Paths == 0 {
+			t.Errorf("leafs %s and %s have no path", r.From, r.To)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package fabric
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+
+	targetv1 "github.com/yndd/target/apis/target/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultInterfaceFormat is the interface naming of platforms which are not in the catalog
+const defaultInterfaceFormat = "int-1/%d"
+
+//go:embed platforms.yaml
+var defaultPlatforms []byte
+
+// PlatformCatalog describes the ports of the platforms per vendor type.
+type PlatformCatalog struct {
+	Platforms []*Platform `json:"platforms,omitempty"`
+}
+
+// Platform describes the ports of a platform of a vendor type.
+type Platform struct {
+	VendorType targetv1.VendorType `json:"vendorType"`
+	Platform   string              `json:"platform"`
+	// Ports is the number of front panel ports
+	Ports uint32 `json:"ports,omitempty"`
+	// InterfaceFormat is the format of the interface name with the port number, e.g. int-1/%d
+	InterfaceFormat string `json:"interfaceFormat,omitempty"`
+	// BreakoutFormat is the format of the interface name with the port and sub-port number,
+	// default the interface format followed by /%d
+	BreakoutFormat string `json:"breakoutFormat,omitempty"`
+	// Speeds are the speeds of the port ranges
+	Speeds []*PortSpeed `json:"speeds,omitempty"`
+	// Positions are the downlink and uplink ports per position, e.g. leaf or spine
+	Positions map[string]*PortLayout `json:"positions,omitempty"`
+	// Breakouts are the breakout modes of the port ranges
+	Breakouts []*Breakout `json:"breakouts,omitempty"`
+}
+
+// PortRange is a range of port numbers, including start and end.
+type PortRange struct {
+	Start uint32 `json:"start"`
+	End   uint32 `json:"end"`
+}
+
+// Contains returns true if the port is in the range.
+func (r *PortRange) Contains(port uint32) bool {
+	return r != nil && port >= r.Start && port <= r.End
+}
+
+// Size returns the number of ports in the range.
+func (r *PortRange) Size() uint32 {
+	if r == nil || r.End < r.Start {
+		return 0
+	}
+	return r.End - r.Start + 1
+}
+
+// PortSpeed is the speed of a range of ports.
+type PortSpeed struct {
+	Ports PortRange `json:"ports"`
+	Speed string    `json:"speed"`
+}
+
+// PortLayout are the downlink and uplink ports of a position.
+type PortLayout struct {
+	Downlinks *PortRange `json:"downlinks,omitempty"`
+	Uplinks   *PortRange `json:"uplinks,omitempty"`
+}
+
+// Breakout is a breakout mode of a range of ports.
+type Breakout struct {
+	Ports PortRange `json:"ports"`
+	// Lanes is the number of sub-ports of a port
+	Lanes uint32 `json:"lanes"`
+	// Speed is the speed of a sub-port
+	Speed string `json:"speed,omitempty"`
+}
+
+var defaultPlatformCatalog = mustLoadPlatformCatalog(defaultPlatforms)
+
+// DefaultPlatformCatalog returns a copy of the embedded platform catalog.
+func DefaultPlatformCatalog() *PlatformCatalog {
+	c := &PlatformCatalog{}
+	c.Add(defaultPlatformCatalog.Platforms...)
+	return c
+}
+
+// LoadPlatformCatalog decodes a platform catalog from JSON or YAML.
+func LoadPlatformCatalog(r io.Reader) (*PlatformCatalog, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	c := &PlatformCatalog{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("cannot decode platform catalog: %w", err)
+	}
+	for _, p := range c.Platforms {
+		if p.VendorType == "" || p.Platform == "" {
+			return nil, fmt.Errorf("platform catalog entries require a vendorType and platform")
+		}
+	}
+	return c, nil
+}
+
+func mustLoadPlatformCatalog(b []byte) *PlatformCatalog {
+	c := &PlatformCatalog{}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		panic(fmt.Sprintf("invalid embedded platform catalog: %s", err))
+	}
+	return c
+}
+
+// Add adds the platforms to the catalog, replacing the platforms of the same vendor type.
+func (c *PlatformCatalog) Add(platforms ...*Platform) {
+	for _, p := range platforms {
+		replaced := false
+		for i, cp := range c.Platforms {
+			if cp.VendorType == p.VendorType && cp.Platform == p.Platform {
+				c.Platforms[i] = p
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Platforms = append(c.Platforms, p)
+		}
+	}
+}
+
+// Get returns the platform of the vendor type.
+func (c *PlatformCatalog) Get(vendorType targetv1.VendorType, platform string) (*Platform, bool) {
+	if c == nil {
+		return nil, false
+	}
+	for _, p := range c.Platforms {
+		if p.VendorType == vendorType && p.Platform == platform {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// WithPlatformCatalog specifies the platform catalog used to name the interfaces,
+// replacing the embedded catalog.
+func WithPlatformCatalog(c *PlatformCatalog) Option {
+	return func(f Fabric) {
+		f.SetPlatformCatalog(c)
+	}
+}
+
+func (f *fabric) SetPlatformCatalog(c *PlatformCatalog) { f.catalog = c }
+
+// GetInterfaceName returns the interface name of the port.
+func (p *Platform) GetInterfaceName(port uint32) string {
+	return p.getInterfaceNamer().GetInterfaceName(port, 0)
+}
+
+// getInterfaceNamer returns the namer of the interface formats of the platform
+func (p *Platform) getInterfaceNamer() InterfaceNamer {
+	return &FormatInterfaceNamer{Format: p.InterfaceFormat, BreakoutFormat: p.BreakoutFormat}
+}
+
+// GetUplinks returns the uplink ports of the position, nil if the position has no uplinks.
+func (p *Platform) GetUplinks(position string) *PortRange {
+	if l, ok := p.Positions[position]; ok {
+		return l.Uplinks
+	}
+	return nil
+}
+
+// GetDownlinks returns the downlink ports of the position, nil if the position has no downlinks.
+func (p *Platform) GetDownlinks(position string) *PortRange {
+	if l, ok := p.Positions[position]; ok {
+		return l.Downlinks
+	}
+	return nil
+}
+
+// GetSpeed returns the speed of the port, empty if unknown.
+func (p *Platform) GetSpeed(port uint32) string {
+	for _, s := range p.Speeds {
+		if s.Ports.Contains(port) {
+			return s.Speed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,113 @@
+package fabric
+
+import (
+	"strings"
+	"testing"
+
+	targetv1 "github.com/yndd/target/apis/target/v1"
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+func TestPlatformUplinks(t *testing.T) {
+	tests := map[string]struct {
+		vendorType targetv1.VendorType
+		platform   string
+		position   topov1alpha1.Position
+		// want is the interface of the first uplink
+		want string
+	}{
+		"IXR-D3 leaf":            {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D3", position: topov1alpha1.PositionLeaf, want: "int-1/27"},
+		"IXR-D3 spine":           {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D3", position: topov1alpha1.PositionSpine, want: "int-1/25"},
+		"IXR-D3L leaf":           {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D3L", position: topov1alpha1.PositionLeaf, want: "int-1/27"},
+		"IXR-D3L spine":          {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D3L", position: topov1alpha1.PositionSpine, want: "int-1/25"},
+		"IXR-D2 leaf":            {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D2", position: topov1alpha1.PositionLeaf, want: "int-1/49"},
+		"IXR-D2L borderleaf":     {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D2L", position: topov1alpha1.PositionBorderLeaf, want: "int-1/49"},
+		"IXR-H2 spine":           {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-H2", position: topov1alpha1.PositionSpine, want: "int-1/97"},
+		"IXR-H3 superspine":      {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-H3", position: topov1alpha1.PositionSuperspine, want: "int-1/29"},
+		"SR-1 superspine":        {vendorType: targetv1.VendorTypeNokiaSROS, platform: "SR-1", position: topov1alpha1.PositionSuperspine, want: "1/1/c9/1"},
+		"position without ports": {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-D2", position: topov1alpha1.PositionSpine, want: "int-1/1"},
+		"not in the catalog":     {vendorType: targetv1.VendorTypeNokiaSRL, platform: "IXR-6e", position: topov1alpha1.PositionSpine, want: "int-1/1"},
+	}
+	f := &fabric{catalog: DefaultPlatformCatalog()}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			vi := &topov1alpha1.FabricTierVendorInfo{VendorType: tc.vendorType, Platform: tc.platform}
+			p, _ := f.catalog.Get(tc.vendorType, tc.platform)
+			n, err := NewNode(&nodeInfo{
+				position:          tc.position,
+				podIndex:          1,
+				planeIndex:        1,
+				relativeNodeIndex: 1,
+				vendorInfo:        vi,
+				platform:          p,
+				namer:             f.getInterfaceNamer(vi, p),
+			})
+			if err != nil {
+				t.Fatalf("cannot create node: %s", err)
+			}
+			if got := n.GetInterfaceNameWithPlatfromOffset(1); got != tc.want {
+				t.Errorf("first uplink = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadPlatformCatalog(t *testing.T) {
+	tests := map[string]struct {
+		catalog string
+		want    []string
+		wantErr bool
+	}{
+		"yaml": {
+			catalog: "platforms:\n- vendorType: nokiaSRL\n  platform: IXR-7220\n  ports: 64\n",
+			want:    []string{"IXR-7220"},
+		},
+		"json": {
+			catalog: `{"platforms": [{"vendorType": "nokiaSRL", "platform": "IXR-7220"}, {"vendorType": "nokiaSROS", "platform": "SR-2s"}]}`,
+			want:    []string{"IXR-7220", "SR-2s"},
+		},
+		"without platform": {
+			catalog: "platforms:\n- vendorType: nokiaSRL\n",
+			wantErr: true,
+		},
+		"invalid": {
+			catalog: "platforms: IXR-7220",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c, err := LoadPlatformCatalog(strings.NewReader(tc.catalog))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("LoadPlatformCatalog() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if len(c.Platforms) != len(tc.want) {
+				t.Fatalf("platforms = %d, want %d", len(c.Platforms), len(tc.want))
+			}
+			for i, want := range tc.want {
+				if got := c.Platforms[i].Platform; got != want {
+					t.Errorf("platform %d = %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPlatformCatalogAdd(t *testing.T) {
+	c := DefaultPlatformCatalog()
+	n := len(c.Platforms)
+	c.Add(&Platform{VendorType: targetv1.VendorTypeNokiaSRL, Platform: "IXR-D3", Ports: 34})
+	if len(c.Platforms) != n {
+		t.Errorf("platforms = %d, want %d, the platform must be replaced", len(c.Platforms), n)
+	}
+	if p, _ := c.Get(targetv1.VendorTypeNokiaSRL, "IXR-D3"); p.Ports != 34 {
+		t.Errorf("IXR-D3 ports = %d, want 34", p.Ports)
+	}
+	// the embedded catalog is not changed
+	if p, _ := DefaultPlatformCatalog().Get(targetv1.VendorTypeNokiaSRL, "IXR-D3"); p.Ports != 32 {
+		t.Errorf("embedded IXR-D3 ports = %d, want 32", p.Ports)
+	}
+}
@@ -0,0 +1,133 @@
+package fabric
+
+import (
+	"fmt"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// AnnotationPodIndexes selects the explicit pod indexes in the template, as a JSON or YAML
+// list with a list of pod indexes per pod definition in template order, e.g. [[], [5, 6]]
+// gives the first 2 pods of the second pod definition pod index 5 and 6
+const AnnotationPodIndexes = "fabric.henderiw.io/pod-indexes"
+
+// podIndexOptions returns the pod index options selected by the template annotations
+func podIndexOptions(annotations map[string]string) ([]Option, error) {
+	v, ok := annotations[AnnotationPodIndexes]
+	if !ok {
+		return nil, nil
+	}
+	indexes := [][]uint32{}
+	if err := yaml.Unmarshal([]byte(v), &indexes); err != nil {
+		return nil, fmt.Errorf("annotation %s must be a list of pod indexes per pod definition: %w", AnnotationPodIndexes, err)
+	}
+	opts := []Option{}
+	for p, idx := range indexes {
+		if len(idx) > 0 {
+			opts = append(opts, WithPodIndexes(p, idx...))
+		}
+	}
+	return opts, nil
+}
+
+// pod is a single pod instance of a pod definition in the fabric template
+type pod struct {
+	// index is the global pod index, unique across all pod definitions
+	index uint32
+	// definition is the index of the pod definition in the fabric template
+	definition int
+	template   *topov1alpha1.PodTemplate
+}
+
+// podIndexAllocator allocates global pod indexes across all pod definitions
+// of a fabric template. Explicit indexes are reserved first, the remaining
+// pods get the lowest free index in template order, starting at 1.
+type podIndexAllocator struct {
+	used map[uint32]string
+	next uint32
+}
+
+func newPodIndexAllocator() *podIndexAllocator {
+	return &podIndexAllocator{
+		used: map[uint32]string{},
+		next: 1,
+	}
+}
+
+// reserve claims an explicit pod index and returns an error if the index
+// is already claimed by another pod
+func (a *podIndexAllocator) reserve(index uint32, owner string) error {
+	if index == 0 {
+		return fmt.Errorf("pod index for %s must be bigger than 0", owner)
+	}
+	if o, ok := a.used[index]; ok {
+		return fmt.Errorf("pod index %d of %s conflicts with %s", index, owner, o)
+	}
+	a.used[index] = owner
+	return nil
+}
+
+// allocate returns the lowest free pod index
+func (a *podIndexAllocator) allocate(owner string) uint32 {
+	for {
+		if _, ok := a.used[a.next]; !ok {
+			break
+		}
+		a.next++
+	}
+	a.used[a.next] = owner
+	return a.next
+}
+
+// allocatePods expands the pod definitions of the template into individual pods
+// with a global pod index. explicit holds the explicit pod indexes per pod definition,
+// pods without an explicit index get one allocated. Only the explicit indexes are stable,
+// the allocated indexes follow the template order, so adding pods to a pod definition
+// renumbers the allocated pods of the pod definitions after it.
+func allocatePods(podTemplates []*topov1alpha1.PodTemplate, explicit map[int][]uint32) ([]*pod, error) {
+	a := newPodIndexAllocator()
+
+	for p, indexes := range explicit {
+		if p < 0 || p >= len(podTemplates) {
+			return nil, fmt.Errorf("pod index for pod definition %d, but template only has %d pod definitions", p, len(podTemplates))
+		}
+		if uint32(len(indexes)) > podTemplates[p].GetPodNumber() {
+			return nil, fmt.Errorf("pod definition %d has %d explicit pod indexes, but only %d pods",
+				p, len(indexes), podTemplates[p].GetPodNumber())
+		}
+	}
+
+	// reserve the explicit indexes first so allocation never hands them out
+	for p, podTemplate := range podTemplates {
+		for i := uint32(0); i < podTemplate.GetPodNumber(); i++ {
+			if int(i) < len(explicit[p]) {
+				if err := a.reserve(explicit[p][i], podOwner(p, i)); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	pods := make([]*pod, 0)
+	for p, podTemplate := range podTemplates {
+		for i := uint32(0); i < podTemplate.GetPodNumber(); i++ {
+			var index uint32
+			if int(i) < len(explicit[p]) {
+				index = explicit[p][i]
+			} else {
+				index = a.allocate(podOwner(p, i))
+			}
+			pods = append(pods, &pod{
+				index:      index,
+				definition: p,
+				template:   podTemplate,
+			})
+		}
+	}
+	return pods, nil
+}
+
+func podOwner(definition int, i uint32) string {
+	return fmt.Sprintf("pod definition %d pod %d", definition, i+1)
+}
@@ -0,0 +1,139 @@
+package fabric
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+const twoPodDefinitionsTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: pods
+  annotations:
+    fabric.henderiw.io/pod-indexes: %q
+spec:
+  properties:
+    fabric:
+      pod:
+      - num: %d
+        tier2:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D2
+      - num: 2
+        tier2:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D2
+`
+
+func TestAllocatePods(t *testing.T) {
+	tests := map[string]struct {
+		firstPods  int
+		annotation string
+		opts       []Option
+		// want are the pod indexes per pod definition
+		want    [][]uint32
+		wantErr bool
+	}{
+		"two definitions": {
+			firstPods:  2,
+			annotation: "[]",
+			want:       [][]uint32{{1, 2}, {3, 4}},
+		},
+		"explicit and implicit": {
+			firstPods:  2,
+			annotation: "[[], [1]]",
+			want:       [][]uint32{{2, 3}, {1, 4}},
+		},
+		"explicit option": {
+			firstPods:  2,
+			annotation: "[]",
+			opts:       []Option{WithPodIndexes(0, 3)},
+			want:       [][]uint32{{3, 1}, {2, 4}},
+		},
+		"option takes precedence over the annotation": {
+			firstPods:  2,
+			annotation: "[[5, 6]]",
+			opts:       []Option{WithPodIndexes(0, 7)},
+			want:       [][]uint32{{7, 1}, {2, 3}},
+		},
+		"duplicate explicit index": {
+			firstPods:  2,
+			annotation: "[[5], [5]]",
+			wantErr:    true,
+		},
+		"zero explicit index": {
+			firstPods:  2,
+			annotation: "[[0]]",
+			wantErr:    true,
+		},
+		"more explicit indexes than pods": {
+			firstPods:  2,
+			annotation: "[[], [5, 6, 7]]",
+			wantErr:    true,
+		},
+		"unknown pod definition": {
+			firstPods:  2,
+			annotation: "[[], [], [5]]",
+			wantErr:    true,
+		},
+		"invalid annotation": {
+			firstPods:  2,
+			annotation: "pod5",
+			wantErr:    true,
+		},
+		// growing the first definition renumbers the implicit pods of the second
+		"growing the first definition": {
+			firstPods:  3,
+			annotation: "[]",
+			want:       [][]uint32{{1, 2, 3}, {4, 5}},
+		},
+		"growing the first definition keeps explicit indexes": {
+			firstPods:  3,
+			annotation: "[[], [3, 4]]",
+			want:       [][]uint32{{1, 2, 5}, {3, 4}},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := newTestFabric(t, fmt.Sprintf(twoPodDefinitionsTemplate, tc.annotation, tc.firstPods), tc.opts...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			got := make([][]uint32, 2)
+			for _, p := range f.(*fabric).pods {
+				got[p.definition] = append(got[p.definition], p.index)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("pod indexes = %v, want %v", got, tc.want)
+			}
+			for _, indexes := range tc.want {
+				for _, idx := range indexes {
+					getTestNode(t, f, fmt.Sprintf("pod%d-leaf2", idx))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package fabric
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortError is an interface allocated on a port that the platform of the node does not have,
+// or on a port that is already used by another link.
+type PortError struct {
+	Node     string `json:"node"`
+	Tier     string `json:"tier"`
+	Platform string `json:"platform"`
+	// Port is the requested port
+	Port uint32 `json:"port"`
+	// Interface is the interface name of the requested port
+	Interface string `json:"interface"`
+	// AvailablePorts is the number of ports of the platform, including the sub-ports of broken out ports
+	AvailablePorts uint32 `json:"availablePorts"`
+	// Peer is the other link already using the port
+	Peer string `json:"peer,omitempty"`
+	// PortType is the port type of the requested port, uplink or downlink
+	PortType string `json:"portType,omitempty"`
+	// Range is the port range of the port type of the position on the platform, which
+	// does not have the requested port
+	Range *PortRange `json:"range,omitempty"`
+}
+
+func (e *PortError) Error() string {
+	if e.Peer != "" {
+		return fmt.Sprintf("node %s (%s, %s): port %d (%s) is already used by %s",
+			e.Node, e.Tier, e.Platform, e.Port, e.Interface, e.Peer)
+	}
+	if e.Range != nil {
+		return fmt.Sprintf("node %s (%s, %s): port %d (%s) is not in the %s ports %d-%d",
+			e.Node, e.Tier, e.Platform, e.Port, e.Interface, e.PortType, e.Range.Start, e.Range.End)
+	}
+	return fmt.Sprintf("node %s (%s, %s): port %d (%s) exceeds the %d available ports",
+		e.Node, e.Tier, e.Platform, e.Port, e.Interface, e.AvailablePorts)
+}
+
+// PortCapacityError lists the interfaces that don't fit on the platforms of the nodes.
+type PortCapacityError struct {
+	Errors []*PortError `json:"errors"`
+}
+
+func (e *PortCapacityError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, pe := range e.Errors {
+		msgs = append(msgs, pe.Error())
+	}
+	return fmt.Sprintf("%d interfaces exceed the port capacity: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// NodePorts are the used and free ports of a node.
+type NodePorts struct {
+	Node     string `json:"node"`
+	Tier     string `json:"tier"`
+	Platform string `json:"platform"`
+	// Ports is the number of ports of the platform, a broken out port has a port per sub-port,
+	// 0 if the platform is not in the catalog
+	Ports uint32   `json:"ports"`
+	Used  []uint32 `json:"used"`
+	Free  []uint32 `json:"free"`
+}
+
+// validatePorts validates every allocated port fits on the platform of the node, is in
+// the uplink or downlink ports of the position of the node it is allocated from and is
+// used only once. Nodes of platforms that are not in the catalog are not validated.
+func (f *fabric) validatePorts() error {
+	perr := &PortCapacityError{}
+	for _, np := range f.usedPorts() {
+		n := np.node
+		p := n.GetPlatformInfo()
+		used := map[uint32]string{}
+		for _, e := range np.endpoints {
+			if owner, ok := used[e.port]; ok {
+				perr.Errors = append(perr.Errors, &PortError{
+					Node:      n.String(),
+					Tier:      n.GetPosition(),
+					Platform:  n.GetPlatform(),
+					Port:      e.port,
+					Interface: n.GetInterfaceName(e.port),
+					Peer:      owner,
+				})
+				continue
+			}
+			used[e.port] = e.link
+			if p == nil || n.GetPortCount() == 0 || isServer(n) {
+				continue
+			}
+			if e.port < 1 || e.port > n.GetPortCount() {
+				perr.Errors = append(perr.Errors, &PortError{
+					Node:           n.String(),
+					Tier:           n.GetPosition(),
+					Platform:       n.GetPlatform(),
+					Port:           e.port,
+					Interface:      n.GetInterfaceName(e.port),
+					AvailablePorts: n.GetPortCount(),
+				})
+				continue
+			}
+			if r := getPortRange(p, n.GetPosition(), e.portType); r != nil && !r.Contains(n.GetPlatformPort(e.port)) {
+				perr.Errors = append(perr.Errors, &PortError{
+					Node:      n.String(),
+					Tier:      n.GetPosition(),
+					Platform:  n.GetPlatform(),
+					Port:      e.port,
+					Interface: n.GetInterfaceName(e.port),
+					PortType:  e.portType,
+					Range:     r,
+				})
+			}
+		}
+	}
+	if len(perr.Errors) > 0 {
+		return perr
+	}
+	return nil
+}
+
+// getPortRange returns the ports of the port type of the position on the platform, nil
+// if the platform has no ports of the port type for the position
+func getPortRange(p *Platform, position, portType string) *PortRange {
+	switch portType {
+	case PortTypeUplink:
+		return p.GetUplinks(position)
+	case PortTypeDownlink:
+		return p.GetDownlinks(position)
+	}
+	return nil
+}
+
+// GetPortReport returns the used and free ports of every node, ordered by node name.
+func (f *fabric) GetPortReport() []*NodePorts {
+	usedPorts := map[string]*nodeEndpoints{}
+	for _, np := range f.usedPorts() {
+		usedPorts[np.node.String()] = np
+	}
+
+	nodes := f.GetNodes()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].String() < nodes[j].String() })
+
+	report := make([]*NodePorts, 0, len(nodes))
+	for _, n := range nodes {
+		np := &NodePorts{
+			Node:     n.String(),
+			Tier:     n.GetPosition(),
+			Platform: n.GetPlatform(),
+			Used:     []uint32{},
+			Free:     []uint32{},
+		}
+		used := map[uint32]bool{}
+		if e, ok := usedPorts[n.String()]; ok {
+			for _, ep := range e.endpoints {
+				if !used[ep.port] {
+					used[ep.port] = true
+					np.Used = append(np.Used, ep.port)
+				}
+			}
+		}
+		sort.Slice(np.Used, func(i, j int) bool { return np.Used[i] < np.Used[j] })
+		if !isServer(n) {
+			np.Ports = n.GetPortCount()
+			for port := uint32(1); port <= np.Ports; port++ {
+				if !used[port] {
+					np.Free = append(np.Free, port)
+				}
+			}
+		}
+		report = append(report, np)
+	}
+	return report
+}
+
+type nodeEndpoints struct {
+	node      Node
+	endpoints []*portEndpoint
+}
+
+type portEndpoint struct {
+	port     uint32
+	portType string
+	link     string
+}
+
+// usedPorts returns the ports used by the links per node, ordered by node name and link
+func (f *fabric) usedPorts() []*nodeEndpoints {
+	links := f.GetLinks()
+	sort.Slice(links, func(i, j int) bool { return linkKey(links[i]) < linkKey(links[j]) })
+
+	nodes := map[string]*nodeEndpoints{}
+	for _, l := range links {
+		for _, n := range []Node{l.From().(Node), l.To().(Node)} {
+			port, err := strconv.Atoi(l.GetEndpointLabel(n.String(), KeyPort))
+			if err != nil {
+				// links which are not added by a Wirer have no port
+				continue
+			}
+			ne, ok := nodes[n.String()]
+			if !ok {
+				ne = &nodeEndpoints{node: n}
+				nodes[n.String()] = ne
+			}
+			ne.endpoints = append(ne.endpoints, &portEndpoint{
+				port:     uint32(port),
+				portType: l.GetEndpointLabel(n.String(), KeyPortType),
+				link:     linkKey(l),
+			})
+		}
+	}
+
+	result := make([]*nodeEndpoints, 0, len(nodes))
+	for _, name := range sortedKeys(nodes) {
+		result = append(result, nodes[name])
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package fabric
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+)
+
+const podTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: pod
+spec:
+  properties:
+    fabric:
+      settings:
+        maxUplinksTier3ToTier2: 2
+      pod:
+      - num: 1
+        tier2:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: %d
+          uplinkPerNode: %d
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+`
+
+// linkWirer returns a Wirer which adds a link from spine1 to leaf1 with the interface indexes
+func linkWirer(fromIfIndex, toIfIndex uint32) Wirer {
+	return WirerFunc(func(w Wiring) error {
+		spine := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, map[string]string{KeyRelativeNodeIndex: "1"}))
+		leaf := w.NodesByLabel(positionSelector(topov1alpha1.PositionLeaf, map[string]string{KeyRelativeNodeIndex: "1"}))
+		w.AddLink(spine[0], leaf[0], fromIfIndex, toIfIndex)
+		return nil
+	})
+}
+
+func TestValidatePorts(t *testing.T) {
+	tests := map[string]struct {
+		leafs         int
+		uplinkPerNode int
+		opts          []Option
+		want          []PortError
+	}{
+		"valid": {
+			leafs:         12,
+			uplinkPerNode: 2,
+		},
+		"spine downlinks exceeded": {
+			leafs:         13,
+			uplinkPerNode: 2,
+			want: []PortError{
+				{Node: "pod1-spine1", Port: 25, PortType: PortTypeDownlink},
+				{Node: "pod1-spine1", Port: 26, PortType: PortTypeDownlink},
+				{Node: "pod1-spine2", Port: 25, PortType: PortTypeDownlink},
+				{Node: "pod1-spine2", Port: 26, PortType: PortTypeDownlink},
+			},
+		},
+		"leaf uplinks exceeded": {
+			leafs:         1,
+			uplinkPerNode: 1,
+			opts:          []Option{WithWirer(TierPairLeafLeaf, linkWirer(20, 7))},
+			want: []PortError{
+				{Node: "pod1-leaf1", Port: 33, AvailablePorts: 32},
+			},
+		},
+		"port used twice": {
+			leafs:         1,
+			uplinkPerNode: 1,
+			opts:          []Option{WithWirer(TierPairLeafLeaf, linkWirer(1, 5))},
+			want: []PortError{
+				{Node: "pod1-spine1", Port: 1, Peer: "pod1-leaf1:int-1/27--pod1-spine1:int-1/1"},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestFabric(t, fmt.Sprintf(podTemplate, tc.leafs, tc.uplinkPerNode), tc.opts...)
+			if len(tc.want) == 0 {
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				return
+			}
+			perr := &PortCapacityError{}
+			if !errors.As(err, &perr) {
+				t.Fatalf("New() error = %v, want a PortCapacityError", err)
+			}
+			if len(perr.Errors) != len(tc.want) {
+				t.Fatalf("port errors = %s, want %d errors", perr, len(tc.want))
+			}
+			for i, want := range tc.want {
+				got := perr.Errors[i]
+				if got.Node != want.Node || got.Port != want.Port || got.PortType != want.PortType ||
+					got.AvailablePorts != want.AvailablePorts || got.Peer != want.Peer {
+					t.Errorf("port error %d = %s, want %s", i, got, &want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package fabric
+
+import (
+	"fmt"
+	"strconv"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"gonum.org/v1/gonum/graph/multi"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KindRailTemplate is the kind of the rail template resources
+const KindRailTemplate = "RailTemplate"
+
+// RailTemplate is a rail-optimized fabric, e.g. for GPU clusters, where NIC k of every
+// server of a scalable unit connects to rail leaf k of the scalable unit and every rail
+// leaf connects to every spine.
+type RailTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec RailTemplateSpec `json:"spec,omitempty"`
+}
+
+// RailTemplateSpec specifies the size of a rail-optimized fabric.
+type RailTemplateSpec struct {
+	// ScalableUnits is the number of scalable units, every scalable unit has its own rail leafs
+	ScalableUnits uint32 `json:"scalableUnits,omitempty"`
+	// ServersPerScalableUnit is the number of servers in every scalable unit
+	ServersPerScalableUnit uint32 `json:"serversPerScalableUnit,omitempty"`
+	// Rails is the number of NICs per server and rail leafs per scalable unit
+	Rails uint32 `json:"rails,omitempty"`
+	// Spines is the number of spines, shared by all scalable units
+	Spines uint32 `json:"spines,omitempty"`
+	// UplinksPerSpine is the number of links between every rail leaf and spine, default 1
+	UplinksPerSpine uint32 `json:"uplinksPerSpine,omitempty"`
+	// LeafVendorInfo is the vendor info of the rail leafs
+	LeafVendorInfo []*topov1alpha1.FabricTierVendorInfo `json:"leafVendorInfo,omitempty"`
+	// SpineVendorInfo is the vendor info of the spines
+	SpineVendorInfo []*topov1alpha1.FabricTierVendorInfo `json:"spineVendorInfo,omitempty"`
+	// ServerPlatform is the platform of the servers
+	ServerPlatform string `json:"serverPlatform,omitempty"`
+}
+
+// NewRail returns a rail-optimized fabric. The scalable units are the pods of the fabric:
+// rail leaf k of scalable unit n is leaf k of pod n, the spines are spines of pod 1 and
+// the servers are servers of the pod, so the allocations and exporters of the pod fabric apply.
+// The Wirers of TierPairSpineLeaf and TierPairLeafServer can be replaced with the options.
+// The servers come from the rail template, so WithServers, WithLeafGroups and explicit
+// vendor assignments, which reference the pod node names, return an error.
+func NewRail(t *RailTemplate, opts ...Option) (Fabric, error) {
+	f := &fabric{
+		graph:           multi.NewUndirectedGraph(),
+		namespace:       t.Namespace,
+		podIndexes:      map[int][]uint32{},
+		outputDir:       DefaultOutputDir,
+		catalog:         DefaultPlatformCatalog(),
+		vendorAssigners: map[topov1alpha1.Position]*vendorAssigner{},
+		wirers: map[TierPair]Wirer{
+			TierPairSpineLeaf:  NewRailSpineWirer(),
+			TierPairLeafServer: NewRailServerWirer(),
+		},
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if err := f.validateRailOptions(); err != nil {
+		return nil, fmt.Errorf("rail template %s: %w", t.GetName(), err)
+	}
+
+	s := t.Spec
+	if s.ScalableUnits == 0 || s.Rails == 0 || s.Spines == 0 {
+		return nil, fmt.Errorf("rail template %s requires scalableUnits, rails and spines", t.GetName())
+	}
+	f.settings = &topov1alpha1.FabricTemplateSettings{
+		MaxUplinksTier2ToTier1: defaultUint32(s.UplinksPerSpine, 1),
+		MaxUplinksTier3ToTier2: defaultUint32(s.UplinksPerSpine, 1),
+		MaxSpinesPerPod:        s.Spines,
+	}
+
+	if err := f.processTier(topov1alpha1.PositionSpine, 1, &topov1alpha1.TierTemplate{
+		NodeNumber: s.Spines,
+		VendorInfo: s.SpineVendorInfo,
+	}, true); err != nil {
+		return nil, err
+	}
+	for su := uint32(1); su <= s.ScalableUnits; su++ {
+		f.pods = append(f.pods, &pod{index: su})
+		if err := f.processTier(topov1alpha1.PositionLeaf, su, &topov1alpha1.TierTemplate{
+			NodeNumber:     s.Rails,
+			UplinksPerNode: defaultUint32(s.UplinksPerSpine, 1),
+			VendorInfo:     s.LeafVendorInfo,
+		}, true); err != nil {
+			return nil, err
+		}
+		for n := uint32(0); n < s.ServersPerScalableUnit; n++ {
+			server, err := NewNode(&nodeInfo{
+				position:          PositionServer,
+				graphIndex:        f.graph.NewNode().ID(),
+				podIndex:          su,
+				rackIndex:         1,
+				relativeNodeIndex: n + 1,
+				vendorInfo:        &topov1alpha1.FabricTierVendorInfo{Platform: s.ServerPlatform},
+				toBeDeployed:      true,
+				location:          f.location,
+			})
+			if err != nil {
+				return nil, err
+			}
+			f.addNode(server)
+		}
+	}
+	f.labelRails()
+
+	if err := f.build(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// validateRailOptions returns an error for the options a rail fabric does not support
+func (f *fabric) validateRailOptions() error {
+	if f.servers != nil {
+		return fmt.Errorf("servers are specified by the rail template, not by a server config")
+	}
+	if f.leafGroups != nil {
+		return fmt.Errorf("rail leafs can not be grouped into leaf groups")
+	}
+	for _, a := range f.vendorAssignments {
+		if a.Strategy == VendorStrategyExplicit {
+			return fmt.Errorf("%s vendor assignment %s is not supported, the rail nodes are named after their rail",
+				a.Position, a.Strategy)
+		}
+	}
+	return nil
+}
+
+// labelRails names the nodes of the rail fabric and labels the rail leafs with their rail
+func (f *fabric) labelRails() {
+	for _, n := range f.GetNodes() {
+		switch n.GetPosition() {
+		case string(topov1alpha1.PositionSpine):
+			n.UpdateLabel(map[string]string{
+				KeyName: "spine" + n.GetRelativeNodeIndex(),
+			})
+		case string(topov1alpha1.PositionLeaf):
+			n.UpdateLabel(map[string]string{
+				KeyName: fmt.Sprintf("su%s-rail%s", n.GetPodIndex(), n.GetRelativeNodeIndex()),
+				KeyRail: n.GetRelativeNodeIndex(),
+			})
+		case string(PositionServer):
+			n.UpdateLabel(map[string]string{
+				KeyName: fmt.Sprintf("su%s-server%s", n.GetPodIndex(), n.GetRelativeNodeIndex()),
+			})
+		}
+	}
+}
+
+// NewRailSpineWirer returns the spine-leaf Wirer of a rail fabric, which connects every rail
+// leaf to every spine. The scalable units are appended on the spine ports, so adding
+// a scalable unit does not reindex the spine ports.
+// spine Index     -> actualUplinkId + ((scalable unit - 1) * rails + rail - 1) * uplinks per spine
+// rail leaf Index -> actualUplinkId + (spine index - 1) * uplinks per spine
+func NewRailSpineWirer() Wirer {
+	return WirerFunc(func(w Wiring) error {
+		settings := w.GetSettings()
+		spines := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, nil))
+		leafs := w.NodesByLabel(positionSelector(topov1alpha1.PositionLeaf, nil))
+
+		// rails per scalable unit
+		rails := 0
+		for _, leaf := range leafs {
+			rail, err := strconv.Atoi(leaf.GetRail())
+			if err != nil {
+				return fmt.Errorf("leaf %s has no rail: %w", leaf.String(), err)
+			}
+			if rail > rails {
+				rails = rail
+			}
+		}
+
+		for _, leaf := range leafs {
+			su, err := strconv.Atoi(leaf.GetPodIndex())
+			if err != nil {
+				return err
+			}
+			rail, err := strconv.Atoi(leaf.GetRail())
+			if err != nil {
+				return err
+			}
+			for _, spine := range spines {
+				spineIndex, err := strconv.Atoi(spine.GetRelativeNodeIndex())
+				if err != nil {
+					return err
+				}
+				for u := uint32(0); u < leaf.GetUplinkPerNode(); u++ {
+					l := w.AddLink(spine, leaf,
+						u+1+uint32((su-1)*rails+rail-1)*settings.MaxUplinksTier2ToTier1,
+						u+1+uint32(spineIndex-1)*settings.MaxUplinksTier2ToTier1,
+					)
+					l.UpdateLabel(map[string]string{KeyRail: leaf.GetRail()})
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// NewRailServerWirer returns the leaf-server Wirer of a rail fabric, which connects NIC k of
+// every server to rail leaf k of its scalable unit.
+// rail leaf Index -> server index
+// server Index    -> rail
+func NewRailServerWirer() Wirer {
+	return WirerFunc(func(w Wiring) error {
+		for _, leaf := range w.NodesByLabel(positionSelector(topov1alpha1.PositionLeaf, nil)) {
+			rail, err := strconv.Atoi(leaf.GetRail())
+			if err != nil {
+				return fmt.Errorf("leaf %s has no rail: %w", leaf.String(), err)
+			}
+			servers := w.NodesByLabel(positionSelector(PositionServer, map[string]string{
+				KeyPodIndex: leaf.GetPodIndex(),
+			}))
+			for _, server := range servers {
+				serverIndex, err := strconv.Atoi(server.GetRelativeNodeIndex())
+				if err != nil {
+					return err
+				}
+				l := w.AddLink(leaf, server, uint32(serverIndex), uint32(rail))
+				l.UpdateLabel(map[string]string{KeyRail: leaf.GetRail()})
+			}
+		}
+		return nil
+	})
+}
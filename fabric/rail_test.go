@@ -0,0 +1,71 @@
+package fabric
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yndd/ndd-runtime/pkg/logging"
+)
+
+const railTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: RailTemplate
+metadata:
+  name: rail
+spec:
+  scalableUnits: 2
+  serversPerScalableUnit: 2
+  rails: 2
+  spines: 2
+  leafVendorInfo:
+  - vendorType: nokiaSRL
+    platform: IXR-D3
+  - vendorType: nokiaSRL
+    platform: IXR-D3L
+  spineVendorInfo:
+  - vendorType: nokiaSRL
+    platform: IXR-D3
+  serverPlatform: dgx
+`
+
+func TestNewRailOptions(t *testing.T) {
+	tests := map[string]struct {
+		opts    []Option
+		wantErr string
+	}{
+		"no options": {},
+		"ratio vendor assignment": {
+			opts: []Option{WithVendorAssignments(&VendorAssignment{Position: "leaf", Strategy: VendorStrategyRatio, Ratio: []uint32{1, 1}})},
+		},
+		"servers": {
+			opts:    []Option{WithServers(&ServerConfig{ServersPerRack: 1})},
+			wantErr: "servers are specified by the rail template",
+		},
+		"leaf groups": {
+			opts:    []Option{WithLeafGroups(&LeafGroupConfig{Size: 2})},
+			wantErr: "can not be grouped into leaf groups",
+		},
+		"explicit vendor assignment": {
+			opts:    []Option{WithVendorAssignments(&VendorAssignment{Position: "leaf", Strategy: VendorStrategyExplicit, Nodes: map[string]uint32{"su1-rail2": 1}})},
+			wantErr: "vendor assignment explicit is not supported",
+		},
+	}
+	res, err := ReadResources(strings.NewReader(railTemplate))
+	if err != nil {
+		t.Fatalf("cannot read rail template: %s", err)
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRail(res.RailTemplates[0], append([]Option{WithLogger(logging.NewNopLogger())}, tc.opts...)...)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewRail() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("NewRail() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
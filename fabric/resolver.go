@@ -0,0 +1,188 @@
+package fabric
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TemplateResolver resolves the templates referenced by a fabric template.
+type TemplateResolver interface {
+	GetTemplate(ctx context.Context, namespace, name string) (*topov1alpha1.Template, error)
+}
+
+// DefinitionResolver resolves the definitions referenced by a fabric template.
+type DefinitionResolver interface {
+	GetDefinition(ctx context.Context, namespace, name string) (*topov1alpha1.Definition, error)
+}
+
+// Resolver resolves both templates and definitions.
+type Resolver interface {
+	TemplateResolver
+	DefinitionResolver
+}
+
+// NewClientResolver returns a Resolver that gets the templates and definitions from the cluster.
+func NewClientResolver(c client.Client) Resolver {
+	return &clientResolver{client: c}
+}
+
+type clientResolver struct {
+	client client.Client
+}
+
+func (r *clientResolver) GetTemplate(ctx context.Context, namespace, name string) (*topov1alpha1.Template, error) {
+	t := &topov1alpha1.Template{}
+	if err := r.client.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *clientResolver) GetDefinition(ctx context.Context, namespace, name string) (*topov1alpha1.Definition, error) {
+	d := &topov1alpha1.Definition{}
+	if err := r.client.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      name,
+	}, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// NewMemoryResolver returns a TemplateResolver that resolves the templates from the given templates.
+// A template without namespace matches any namespace.
+func NewMemoryResolver(templates ...*topov1alpha1.Template) TemplateResolver {
+	return &memoryResolver{res: &Resources{Templates: templates}}
+}
+
+// NewResourcesResolver returns a Resolver that resolves the templates and definitions
+// from the given resources, e.g. the resources read with ReadResources.
+// A resource without namespace matches any namespace.
+func NewResourcesResolver(res *Resources) Resolver {
+	return &memoryResolver{res: res}
+}
+
+type memoryResolver struct {
+	res *Resources
+}
+
+func (r *memoryResolver) GetTemplate(ctx context.Context, namespace, name string) (*topov1alpha1.Template, error) {
+	return findTemplate(r.res.Templates, namespace, name)
+}
+
+func (r *memoryResolver) GetDefinition(ctx context.Context, namespace, name string) (*topov1alpha1.Definition, error) {
+	return findDefinition(r.res.Definitions, namespace, name)
+}
+
+// NewDirResolver returns a Resolver that resolves the templates and definitions from the JSON
+// and YAML files in a directory. The directory is read on every call, so files can be changed
+// in between builds.
+func NewDirResolver(dir string) Resolver {
+	return &dirResolver{dir: dir}
+}
+
+type dirResolver struct {
+	dir string
+}
+
+func (r *dirResolver) GetTemplate(ctx context.Context, namespace, name string) (*topov1alpha1.Template, error) {
+	res, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	return findTemplate(res.Templates, namespace, name)
+}
+
+func (r *dirResolver) GetDefinition(ctx context.Context, namespace, name string) (*topov1alpha1.Definition, error) {
+	res, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	return findDefinition(res.Definitions, namespace, name)
+}
+
+func (r *dirResolver) read() (*Resources, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Resources{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		fr, err := readResourcesFile(filepath.Join(r.dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		res.Templates = append(res.Templates, fr.Templates...)
+		res.Definitions = append(res.Definitions, fr.Definitions...)
+	}
+	return res, nil
+}
+
+func readResourcesFile(path string) (*Resources, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	res, err := ReadResources(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read resources from %s: %w", path, err)
+	}
+	return res, nil
+}
+
+func findTemplate(templates []*topov1alpha1.Template, namespace, name string) (*topov1alpha1.Template, error) {
+	for _, t := range templates {
+		if matchNamespacedName(t.GetNamespace(), t.GetName(), namespace, name) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s/%s not found", KindTemplate, namespace, name)
+}
+
+func findDefinition(definitions []*topov1alpha1.Definition, namespace, name string) (*topov1alpha1.Definition, error) {
+	for _, d := range definitions {
+		if matchNamespacedName(d.GetNamespace(), d.GetName(), namespace, name) {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s/%s not found", KindDefinition, namespace, name)
+}
+
+// matchNamespacedName returns true if the resource matches the namespace and name,
+// an empty namespace matches any namespace
+func matchNamespacedName(resNamespace, resName, namespace, name string) bool {
+	if resName != name {
+		return false
+	}
+	return resNamespace == "" || namespace == "" || resNamespace == namespace
+}
+
+// splitNamespacedName splits a namespace/name reference, a reference without
+// namespace returns an empty namespace
+func splitNamespacedName(nsn string) (string, string) {
+	if namespace, name, ok := strings.Cut(nsn, "/"); ok {
+		return namespace, name
+	}
+	return "", nsn
+}
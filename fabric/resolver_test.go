@@ -0,0 +1,269 @@
+package fabric
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yndd/ndd-runtime/pkg/logging"
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// writeTestFiles writes the files to a temporary directory and returns the directory
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("cannot write %s: %s", name, err)
+		}
+	}
+	return dir
+}
+
+func TestTemplateResolvers(t *testing.T) {
+	res, err := ReadResources(strings.NewReader(mixedResources))
+	if err != nil {
+		t.Fatalf("cannot read resources: %s", err)
+	}
+	dir := writeTestFiles(t, map[string]string{
+		"resources.yaml": mixedResources,
+		"notes.txt":      "not a resource",
+	})
+	resolvers := map[string]TemplateResolver{
+		"memory":    NewMemoryResolver(res.Templates...),
+		"resources": NewResourcesResolver(res),
+		"dir":       NewDirResolver(dir),
+	}
+	tests := map[string]struct {
+		namespace string
+		name      string
+		wantErr   string
+	}{
+		"namespaced":                 {namespace: "default", name: "pod"},
+		"any namespace":              {name: "pod"},
+		"template without namespace": {namespace: "other", name: "fabric"},
+		"other namespace":            {namespace: "other", name: "pod", wantErr: "Template other/pod not found"},
+		"not found":                  {namespace: "default", name: "leaf", wantErr: "Template default/leaf not found"},
+	}
+	for rname, r := range resolvers {
+		for name, tc := range tests {
+			t.Run(rname+"/"+name, func(t *testing.T) {
+				got, err := r.GetTemplate(context.TODO(), tc.namespace, tc.name)
+				if tc.wantErr != "" {
+					if err == nil || err.Error() != tc.wantErr {
+						t.Fatalf("GetTemplate() error = %v, want %q", err, tc.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GetTemplate() error = %v", err)
+				}
+				if got.GetName() != tc.name {
+					t.Errorf("GetTemplate() = %s, want %s", got.GetName(), tc.name)
+				}
+			})
+		}
+	}
+}
+
+func TestDefinitionResolvers(t *testing.T) {
+	res, err := ReadResources(strings.NewReader(mixedResources))
+	if err != nil {
+		t.Fatalf("cannot read resources: %s", err)
+	}
+	resolvers := map[string]DefinitionResolver{
+		"resources": NewResourcesResolver(res),
+		"dir":       NewDirResolver(writeTestFiles(t, map[string]string{"resources.yml": mixedResources})),
+	}
+	tests := map[string]struct {
+		namespace string
+		name      string
+		wantErr   string
+	}{
+		"namespaced": {namespace: "default", name: "dc1"},
+		"not found":  {namespace: "default", name: "dc2", wantErr: "Definition default/dc2 not found"},
+	}
+	for rname, r := range resolvers {
+		for name, tc := range tests {
+			t.Run(rname+"/"+name, func(t *testing.T) {
+				got, err := r.GetDefinition(context.TODO(), tc.namespace, tc.name)
+				if tc.wantErr != "" {
+					if err == nil || err.Error() != tc.wantErr {
+						t.Fatalf("GetDefinition() error = %v, want %q", err, tc.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("GetDefinition() error = %v", err)
+				}
+				if got.GetName() != tc.name {
+					t.Errorf("GetDefinition() = %s, want %s", got.GetName(), tc.name)
+				}
+			})
+		}
+	}
+}
+
+func TestDirResolverErrors(t *testing.T) {
+	tests := map[string]struct {
+		dir     string
+		wantErr string
+	}{
+		"missing directory": {
+			dir:     filepath.Join(t.TempDir(), "missing"),
+			wantErr: "no such file or directory",
+		},
+		"invalid file": {
+			dir:     writeTestFiles(t, map[string]string{"pod.json": "{"}),
+			wantErr: "cannot read resources from",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewDirResolver(tc.dir).GetTemplate(context.TODO(), "default", "pod")
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("GetTemplate() error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDirResolverReadsChanges(t *testing.T) {
+	dir := t.TempDir()
+	r := NewDirResolver(dir)
+	if _, err := r.GetTemplate(context.TODO(), "default", "pod"); err == nil {
+		t.Fatalf("GetTemplate() error = nil, want not found in an empty directory")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pod.yaml"), []byte(mixedResources), 0o644); err != nil {
+		t.Fatalf("cannot write pod.yaml: %s", err)
+	}
+	if _, err := r.GetTemplate(context.TODO(), "default", "pod"); err != nil {
+		t.Errorf("GetTemplate() error = %v, want the template of the new file", err)
+	}
+}
+
+func TestSplitNamespacedName(t *testing.T) {
+	tests := map[string]struct {
+		nsn           string
+		wantNamespace string
+		wantName      string
+	}{
+		"namespaced":      {nsn: "default/dc1", wantNamespace: "default", wantName: "dc1"},
+		"name":            {nsn: "dc1", wantName: "dc1"},
+		"empty":           {},
+		"empty namespace": {nsn: "/dc1", wantName: "dc1"},
+		"nested":          {nsn: "default/dc1/pod", wantNamespace: "default", wantName: "dc1/pod"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			namespace, n := splitNamespacedName(tc.nsn)
+			if namespace != tc.wantNamespace || n != tc.wantName {
+				t.Errorf("splitNamespacedName(%q) = %q, %q, want %q, %q", tc.nsn, namespace, n, tc.wantNamespace, tc.wantName)
+			}
+		})
+	}
+}
+
+const referencedResources = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: pod
+  namespace: default
+spec:
+  properties:
+    fabric:
+      pod:
+      - num: 1
+        tier2:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D2
+---
+apiVersion: topo.yndd.io/v1alpha1
+kind: Definition
+metadata:
+  name: dc1
+  namespace: default
+spec:
+  properties:
+    templates:
+    - namespacedName: pod
+`
+
+func TestDefinitionReferences(t *testing.T) {
+	res, err := ReadResources(strings.NewReader(referencedResources))
+	if err != nil {
+		t.Fatalf("cannot read resources: %s", err)
+	}
+	tests := map[string]struct {
+		ref     string
+		opts    []Option
+		wantErr bool
+	}{
+		"name":               {ref: "dc1", opts: []Option{WithTemplateResolver(NewResourcesResolver(res))}},
+		"namespaced name":    {ref: "default/dc1", opts: []Option{WithTemplateResolver(NewResourcesResolver(res))}},
+		"unknown definition": {ref: "default/dc2", opts: []Option{WithTemplateResolver(NewResourcesResolver(res))}, wantErr: true},
+		"definition resolver": {
+			ref: "dc1",
+			opts: []Option{
+				WithTemplateResolver(NewMemoryResolver(res.Templates...)),
+				WithDefinitionResolver(NewResourcesResolver(res)),
+			},
+		},
+		"template resolver without definitions": {
+			ref:     "dc1",
+			opts:    []Option{WithTemplateResolver(NewMemoryResolver(res.Templates...))},
+			wantErr: true,
+		},
+		"no resolver": {
+			ref:     "dc1",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ref := tc.ref
+			tmpl := &topov1alpha1.Template{
+				ObjectMeta: metav1.ObjectMeta{Name: "fabric", Namespace: "default"},
+				Spec: topov1alpha1.TemplateSpec{Properties: &topov1alpha1.TemplateProperties{Fabric: &topov1alpha1.FabricTemplate{
+					Pod: []*topov1alpha1.PodTemplate{
+						{TemplateRef: &corev1.ObjectReference{Name: "pod"}},
+						{DefinitionReference: &ref},
+					},
+				}}},
+			}
+			f, err := New(tmpl, append([]Option{WithLogger(logging.NewNopLogger())}, tc.opts...)...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			// the pods of a definition are already deployed
+			for node, want := range map[string]bool{
+				"pod1-leaf1":  true,
+				"pod1-spine2": true,
+				"pod2-leaf1":  false,
+				"pod2-spine2": false,
+			} {
+				if got := getTestNode(t, f, node).IsToBeDeployed(); got != want {
+					t.Errorf("%s toBeDeployed = %t, want %t", node, got, want)
+				}
+			}
+		})
+	}
+}
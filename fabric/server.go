@@ -0,0 +1,222 @@
+package fabric
+
+import (
+	"fmt"
+	"strconv"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// PositionServer is the position of the servers attached to the leafs
+const PositionServer topov1alpha1.Position = "server"
+
+// AnnotationServers declares the servers in the template, as a JSON or YAML ServerConfig
+const AnnotationServers = "fabric.henderiw.io/servers"
+
+// ServerHoming specifies to how many leafs a server is attached.
+type ServerHoming string
+
+const (
+	// ServerHomingSingle attaches every server to one leaf, a rack has one leaf
+	ServerHomingSingle ServerHoming = "single"
+	// ServerHomingDual attaches every server to two leafs, a rack has two leafs
+	ServerHomingDual ServerHoming = "dual"
+)
+
+// ServerConfig adds racks of servers below the leafs. The leafs of a pod are grouped into
+// racks by relative index, with dual homing leaf1 and leaf2 are rack 1, leaf3 and leaf4 are rack 2, etc.
+type ServerConfig struct {
+	// ServersPerRack is the number of servers in every rack
+	ServersPerRack uint32 `json:"serversPerRack,omitempty"`
+	// NICsPerServer is the number of NICs of a server, spread evenly over the leafs of the rack,
+	// default 1 per leaf
+	NICsPerServer uint32 `json:"nicsPerServer,omitempty"`
+	// Homing specifies to how many leafs a server is attached, default single
+	Homing ServerHoming `json:"homing,omitempty"`
+	// AccessPortStart is the first access port index of the leafs, default 1
+	AccessPortStart uint32 `json:"accessPortStart,omitempty"`
+	// AccessPortEnd is the last access port index of the leafs, 0 is unlimited
+	AccessPortEnd uint32 `json:"accessPortEnd,omitempty"`
+	// Platform is the platform of the servers
+	Platform string `json:"platform,omitempty"`
+}
+
+// WithServers adds racks of servers below the leafs of every pod.
+func WithServers(c *ServerConfig) Option {
+	return func(f Fabric) {
+		f.SetServers(c)
+	}
+}
+
+func (f *fabric) SetServers(c *ServerConfig) {
+	f.servers = c
+	if c != nil {
+		f.SetWirer(TierPairLeafServer, NewServerWirer(c))
+	}
+}
+
+// serverOptions returns the server option declared by the template annotations
+func serverOptions(annotations map[string]string) ([]Option, error) {
+	v, ok := annotations[AnnotationServers]
+	if !ok {
+		return nil, nil
+	}
+	c := &ServerConfig{}
+	if err := yaml.Unmarshal([]byte(v), c); err != nil {
+		return nil, fmt.Errorf("annotation %s must be a server config: %w", AnnotationServers, err)
+	}
+	return []Option{WithServers(c)}, nil
+}
+
+// leafsPerRack returns the number of leafs a server is attached to
+func (c *ServerConfig) leafsPerRack() (uint32, error) {
+	switch c.Homing {
+	case "", ServerHomingSingle:
+		return 1, nil
+	case ServerHomingDual:
+		return 2, nil
+	}
+	return 0, fmt.Errorf("unknown server homing %s", c.Homing)
+}
+
+// nicsPerLeaf returns the number of NICs of a server attached to every leaf of the rack
+func (c *ServerConfig) nicsPerLeaf() (uint32, error) {
+	leafsPerRack, err := c.leafsPerRack()
+	if err != nil {
+		return 0, err
+	}
+	if c.NICsPerServer == 0 {
+		return 1, nil
+	}
+	if c.NICsPerServer%leafsPerRack != 0 {
+		return 0, fmt.Errorf("%d NICs per server can not be spread evenly over %d leafs", c.NICsPerServer, leafsPerRack)
+	}
+	return c.NICsPerServer / leafsPerRack, nil
+}
+
+// processServers adds the servers of every rack of every pod
+func (f *fabric) processServers() error {
+	if f.servers == nil {
+		return nil
+	}
+	leafsPerRack, err := f.servers.leafsPerRack()
+	if err != nil {
+		return err
+	}
+	if _, err := f.servers.nicsPerLeaf(); err != nil {
+		return err
+	}
+	if err := f.validateAccessPorts(); err != nil {
+		return err
+	}
+	if leafsPerRack > 1 && f.leafGroups != nil && f.leafGroups.Size != leafsPerRack {
+		return fmt.Errorf("leaf group size %d does not match the %d leafs per rack of %s homed servers",
+			f.leafGroups.Size, leafsPerRack, f.servers.Homing)
+	}
+
+	for _, pod := range f.pods {
+		leafs := f.nodesByLabel(positionSelector(topov1alpha1.PositionLeaf, map[string]string{
+			KeyPodIndex: strconv.Itoa(int(pod.index)),
+		}))
+		if len(leafs)%int(leafsPerRack) != 0 {
+			return fmt.Errorf("pod %d has %d leafs, which is not a multiple of %d leafs per rack", pod.index, len(leafs), leafsPerRack)
+		}
+		racks := uint32(len(leafs)) / leafsPerRack
+		for r := uint32(0); r < racks; r++ {
+			for s := uint32(0); s < f.servers.ServersPerRack; s++ {
+				n, err := NewNode(&nodeInfo{
+					position:          PositionServer,
+					graphIndex:        f.graph.NewNode().ID(),
+					podIndex:          pod.index,
+					rackIndex:         r + 1,
+					relativeNodeIndex: s + 1,
+					vendorInfo:        &topov1alpha1.FabricTierVendorInfo{Platform: f.servers.Platform},
+					toBeDeployed:      pod.template.IsToBeDeployed(),
+					location:          f.location,
+				})
+				if err != nil {
+					return err
+				}
+				f.addNode(n)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAccessPorts validates the access ports of the servers fit in the access port range
+// and don't overlap with the ISL ports of the leaf groups
+func (f *fabric) validateAccessPorts() error {
+	nicsPerLeaf, err := f.servers.nicsPerLeaf()
+	if err != nil {
+		return err
+	}
+	if f.servers.ServersPerRack == 0 {
+		return nil
+	}
+	start := defaultUint32(f.servers.AccessPortStart, 1)
+	last := start + f.servers.ServersPerRack*nicsPerLeaf - 1
+	if f.servers.AccessPortEnd != 0 && last > f.servers.AccessPortEnd {
+		return fmt.Errorf("%d servers per rack with %d NICs per leaf need access ports %d-%d, which exceeds the access port end %d",
+			f.servers.ServersPerRack, nicsPerLeaf, start, last, f.servers.AccessPortEnd)
+	}
+	if g := f.leafGroups; g != nil && g.ISLsPerPeer > 0 {
+		islLast := g.ISLInterfaceStart + (g.Size-1)*g.ISLsPerPeer - 1
+		if g.ISLInterfaceStart <= last && start <= islLast {
+			return fmt.Errorf("access ports %d-%d overlap with the leaf ISL ports %d-%d", start, last, g.ISLInterfaceStart, islLast)
+		}
+	}
+	return nil
+}
+
+// NewServerWirer returns a leaf-server Wirer, which connects every server to the leafs of its rack.
+// leaf Index   -> accessPortStart + (server index - 1) * nics per leaf + actualNicId
+// server Index -> actualNicId + 1 + leaf index within the rack * nics per leaf
+// actualNicId  -> counting from 0
+func NewServerWirer(c *ServerConfig) Wirer {
+	return WirerFunc(func(w Wiring) error {
+		leafsPerRack, err := c.leafsPerRack()
+		if err != nil {
+			return err
+		}
+		nicsPerLeaf, err := c.nicsPerLeaf()
+		if err != nil {
+			return err
+		}
+		start := defaultUint32(c.AccessPortStart, 1)
+
+		for _, server := range w.NodesByLabel(positionSelector(PositionServer, nil)) {
+			rackIndex, err := strconv.Atoi(server.GetRackIndex())
+			if err != nil {
+				return err
+			}
+			serverIndex, err := strconv.Atoi(server.GetRelativeNodeIndex())
+			if err != nil {
+				return err
+			}
+			for l := uint32(0); l < leafsPerRack; l++ {
+				leafIndex := uint32(rackIndex-1)*leafsPerRack + l + 1
+				leafs := w.NodesByLabel(positionSelector(topov1alpha1.PositionLeaf, map[string]string{
+					KeyPodIndex:          server.GetPodIndex(),
+					KeyRelativeNodeIndex: strconv.Itoa(int(leafIndex)),
+				}))
+				if len(leafs) != 1 {
+					return fmt.Errorf("server %s has no leaf %d in pod %s", server.String(), leafIndex, server.GetPodIndex())
+				}
+				for k := uint32(0); k < nicsPerLeaf; k++ {
+					w.AddLink(leafs[0], server,
+						start+uint32(serverIndex-1)*nicsPerLeaf+k,
+						k+1+l*nicsPerLeaf,
+					)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// isServer returns true if the node is a server, servers have no fabric addresses and ASNs
+func isServer(n Node) bool {
+	return n.GetPosition() == string(PositionServer)
+}
@@ -0,0 +1,46 @@
+package fabric
+
+import (
+	"testing"
+)
+
+func TestServerAnnotations(t *testing.T) {
+	tests := map[string]struct {
+		annotations []string
+		wantServers int
+		wantErr     bool
+	}{
+		"single homed": {
+			annotations: []string{AnnotationServers + ": '{serversPerRack: 2}'"},
+			wantServers: 16,
+		},
+		"dual homed": {
+			annotations: []string{AnnotationServers + ": '{serversPerRack: 3, homing: dual, nicsPerServer: 2}'"},
+			wantServers: 12,
+		},
+		"invalid": {
+			annotations: []string{AnnotationServers + ": '[2]'"},
+			wantErr:     true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := newTestFabric(t, annotatedExampleTemplate(t, tc.annotations...))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			servers := 0
+			for _, n := range f.GetNodes() {
+				if isServer(n) {
+					servers++
+				}
+			}
+			if servers != tc.wantServers {
+				t.Errorf("servers = %d, want %d", servers, tc.wantServers)
+			}
+		})
+	}
+}
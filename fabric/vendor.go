@@ -0,0 +1,235 @@
+package fabric
+
+import (
+	"fmt"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+// AnnotationVendorAssignments selects the vendor assignments in the template, as a JSON or YAML list of VendorAssignments
+const AnnotationVendorAssignments = "fabric.henderiw.io/vendor-assignments"
+
+// VendorStrategy is how the vendor infos of a tier are assigned to the nodes of the tier.
+type VendorStrategy string
+
+const (
+	// VendorStrategyRoundRobin assigns the vendor infos in turn by relative node index,
+	// e.g. with 2 vendors the odd nodes get vendor A and the even nodes vendor B
+	VendorStrategyRoundRobin VendorStrategy = "roundRobin"
+	// VendorStrategyExplicit assigns the vendor info per node name, the nodes which are
+	// not listed get the first vendor info
+	VendorStrategyExplicit VendorStrategy = "explicit"
+	// VendorStrategyRatio spreads the vendor infos over the nodes of a position by weight,
+	// across all pods or planes, e.g. a ratio of 3:1 assigns vendor A, A, B, A to 4 nodes
+	VendorStrategyRatio VendorStrategy = "ratio"
+	// VendorStrategyPerIndex assigns one vendor info to all nodes of a pod, or a plane for
+	// superspines. The indexes map the pod or plane index to a vendor info, the indexes which
+	// are not listed get the first vendor info. Without indexes the pods or planes get the
+	// vendor infos in turn.
+	VendorStrategyPerIndex VendorStrategy = "perIndex"
+	// VendorStrategyRedundancyGroup assigns every vendor info once per leaf redundancy group,
+	// the leaf groups must be at least as large as the number of vendor infos
+	VendorStrategyRedundancyGroup VendorStrategy = "redundancyGroup"
+)
+
+// VendorAssignment is the vendor assignment of the nodes of a position. The vendor infos
+// are referenced by their index in the vendorInfo list of the tier, counting from 0.
+type VendorAssignment struct {
+	// Position is the position of the nodes, e.g. leaf
+	Position string         `json:"position"`
+	Strategy VendorStrategy `json:"strategy"`
+	// Ratio is the weight of every vendor info for VendorStrategyRatio
+	Ratio []uint32 `json:"ratio,omitempty"`
+	// Indexes maps a pod or plane index to a vendor info for VendorStrategyPerIndex
+	Indexes map[uint32]uint32 `json:"indexes,omitempty"`
+	// Nodes maps a node name to a vendor info for VendorStrategyExplicit
+	Nodes map[string]uint32 `json:"nodes,omitempty"`
+}
+
+// WithVendorAssignments specifies how the vendor infos are assigned to the nodes per position,
+// the positions without an assignment use VendorStrategyRoundRobin.
+func WithVendorAssignments(a ...*VendorAssignment) Option {
+	return func(f Fabric) {
+		f.SetVendorAssignments(a...)
+	}
+}
+
+func (f *fabric) SetVendorAssignments(a ...*VendorAssignment) { f.vendorAssignments = a }
+
+// vendorAssignmentOptions returns the vendor assignment option selected by the template annotations
+func vendorAssignmentOptions(annotations map[string]string) ([]Option, error) {
+	v, ok := annotations[AnnotationVendorAssignments]
+	if !ok {
+		return nil, nil
+	}
+	a := []*VendorAssignment{}
+	if err := yaml.Unmarshal([]byte(v), &a); err != nil {
+		return nil, fmt.Errorf("annotation %s must be a list of vendor assignments: %w", AnnotationVendorAssignments, err)
+	}
+	return []Option{WithVendorAssignments(a...)}, nil
+}
+
+// getVendorAssignment returns the vendor assignment of the position, nil if there is none
+func (f *fabric) getVendorAssignment(position topov1alpha1.Position) *VendorAssignment {
+	for _, a := range f.vendorAssignments {
+		if a.Position == string(position) {
+			return a
+		}
+	}
+	return nil
+}
+
+// vendorAssigner assigns the vendor infos to the nodes of a position. One assigner is used
+// for all tiers of the position, so the ratio is kept across the pods and planes.
+type vendorAssigner struct {
+	position   topov1alpha1.Position
+	assignment *VendorAssignment
+	groupSize  uint32
+	// current are the current weights of the smooth weighted round robin of VendorStrategyRatio
+	current []int64
+}
+
+// getVendorAssigner returns the vendor assigner of the position, validated for a tier with
+// vendorNum vendor infos
+func (f *fabric) getVendorAssigner(position topov1alpha1.Position, vendorNum uint32) (*vendorAssigner, error) {
+	va, ok := f.vendorAssigners[position]
+	if !ok {
+		var err error
+		va, err = f.newVendorAssigner(position)
+		if err != nil {
+			return nil, err
+		}
+		f.vendorAssigners[position] = va
+	}
+	if err := va.validate(vendorNum); err != nil {
+		return nil, err
+	}
+	return va, nil
+}
+
+// newVendorAssigner returns the vendor assigner of the position
+func (f *fabric) newVendorAssigner(position topov1alpha1.Position) (*vendorAssigner, error) {
+	a := f.getVendorAssignment(position)
+	if a == nil {
+		a = &VendorAssignment{Position: string(position), Strategy: VendorStrategyRoundRobin}
+	}
+	va := &vendorAssigner{position: position, assignment: a}
+
+	switch a.Strategy {
+	case VendorStrategyRoundRobin, "", VendorStrategyExplicit, VendorStrategyPerIndex:
+	case VendorStrategyRatio:
+		total := uint32(0)
+		for _, w := range a.Ratio {
+			total += w
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("%s vendor assignment ratio requires a weight", position)
+		}
+		va.current = make([]int64, len(a.Ratio))
+	case VendorStrategyRedundancyGroup:
+		if position != topov1alpha1.PositionLeaf || f.leafGroups == nil {
+			return nil, fmt.Errorf("%s vendor assignment %s requires leaf groups", position, a.Strategy)
+		}
+		va.groupSize = f.leafGroups.Size
+	default:
+		return nil, fmt.Errorf("unknown %s vendor assignment strategy %s", position, a.Strategy)
+	}
+	return va, nil
+}
+
+// validate validates the vendor assignment references the vendor infos of a tier with
+// vendorNum vendor infos
+func (va *vendorAssigner) validate(vendorNum uint32) error {
+	a := va.assignment
+	validIndex := func(idx uint32, ref string) error {
+		if idx >= vendorNum {
+			return fmt.Errorf("%s vendor assignment of %s references vendor info %d, the tier has %d vendor infos",
+				va.position, ref, idx, vendorNum)
+		}
+		return nil
+	}
+	switch a.Strategy {
+	case VendorStrategyExplicit:
+		for _, name := range sortedKeys(a.Nodes) {
+			if err := validIndex(a.Nodes[name], name); err != nil {
+				return err
+			}
+		}
+	case VendorStrategyRatio:
+		if len(a.Ratio) != int(vendorNum) {
+			return fmt.Errorf("%s vendor assignment ratio has %d weights, the tier has %d vendor infos",
+				va.position, len(a.Ratio), vendorNum)
+		}
+	case VendorStrategyPerIndex:
+		for index, idx := range a.Indexes {
+			if err := validIndex(idx, fmt.Sprintf("index %d", index)); err != nil {
+				return err
+			}
+		}
+	case VendorStrategyRedundancyGroup:
+		if va.groupSize < vendorNum {
+			return fmt.Errorf("leaf group size %d can not have one leaf of each of the %d vendor infos",
+				va.groupSize, vendorNum)
+		}
+	}
+	return nil
+}
+
+// next returns the index of the vendor info of the node of a tier with vendorNum vendor infos,
+// nodes must be passed in pod or plane order and relative node index order
+func (va *vendorAssigner) next(ni *nodeInfo, vendorNum uint32) uint32 {
+	a := va.assignment
+	switch a.Strategy {
+	case VendorStrategyExplicit:
+		return a.Nodes[ni.getName()]
+	case VendorStrategyRatio:
+		// smooth weighted round robin, so the vendors are spread over the position
+		var total int64
+		best := 0
+		for i, w := range a.Ratio {
+			va.current[i] += int64(w)
+			total += int64(w)
+			if va.current[i] > va.current[best] {
+				best = i
+			}
+		}
+		va.current[best] -= total
+		return uint32(best)
+	case VendorStrategyPerIndex:
+		index := ni.podIndex
+		if ni.position == topov1alpha1.PositionSuperspine {
+			index = ni.planeIndex
+		}
+		if a.Indexes != nil {
+			return a.Indexes[index]
+		}
+		if index == 0 {
+			return 0
+		}
+		return (index - 1) % vendorNum
+	case VendorStrategyRedundancyGroup:
+		return ((ni.relativeNodeIndex - 1) % va.groupSize) % vendorNum
+	}
+	return (ni.relativeNodeIndex - 1) % vendorNum
+}
+
+// validateVendorAssignments validates the nodes of the explicit vendor assignments exist
+// and have the position of the assignment
+func (f *fabric) validateVendorAssignments() error {
+	positions := map[string]string{}
+	for _, n := range f.GetNodes() {
+		positions[n.String()] = n.GetPosition()
+	}
+	for _, a := range f.vendorAssignments {
+		if a.Strategy != VendorStrategyExplicit {
+			continue
+		}
+		for _, name := range sortedKeys(a.Nodes) {
+			if positions[name] != a.Position {
+				return fmt.Errorf("%s vendor assignment references node %s, which is not a %s", a.Position, name, a.Position)
+			}
+		}
+	}
+	return nil
+}
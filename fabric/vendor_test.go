@@ -0,0 +1,201 @@
+package fabric
+
+import (
+	"fmt"
+	"testing"
+)
+
+const vendorTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: vendor
+spec:
+  properties:
+    fabric:
+      settings:
+        maxUplinksTier2ToTier1: 1
+        maxUplinksTier3ToTier2: 1
+        maxSpinesPerPod: 2
+      tier1:
+        num: 1
+        vendorInfo:
+        - vendorType: nokiaSRL
+          platform: IXR-D3
+      pod:
+      - num: 4
+        tier2:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: %d
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3L
+          - vendorType: nokiaSRL
+            platform: IXR-D2
+`
+
+func TestVendorAssignments(t *testing.T) {
+	tests := map[string]struct {
+		leafs      int
+		assignment *VendorAssignment
+		opts       []Option
+		// want are the platforms of the nodes
+		want map[string]string
+		// wantCounts are the number of leafs per platform
+		wantCounts map[string]int
+		wantErr    bool
+	}{
+		"roundRobin": {
+			leafs: 2,
+			want: map[string]string{
+				"pod1-leaf1": "IXR-D3L",
+				"pod1-leaf2": "IXR-D2",
+				"pod4-leaf1": "IXR-D3L",
+				"pod4-leaf2": "IXR-D2",
+			},
+		},
+		"explicit": {
+			leafs: 2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyExplicit, Nodes: map[string]uint32{
+				"pod2-leaf1": 1,
+				"pod3-leaf2": 0,
+			}},
+			want: map[string]string{
+				"pod1-leaf1": "IXR-D3L",
+				"pod1-leaf2": "IXR-D3L",
+				"pod2-leaf1": "IXR-D2",
+				"pod3-leaf2": "IXR-D3L",
+			},
+		},
+		"explicit unknown vendor info": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyExplicit, Nodes: map[string]uint32{"pod2-leaf1": 2}},
+			wantErr:    true,
+		},
+		"explicit node of another position": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyExplicit, Nodes: map[string]uint32{"pod2-spine1": 1}},
+			wantErr:    true,
+		},
+		"ratio within a pod": {
+			leafs:      4,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyRatio, Ratio: []uint32{3, 1}},
+			want: map[string]string{
+				"pod1-leaf1": "IXR-D3L",
+				"pod1-leaf2": "IXR-D3L",
+				"pod1-leaf3": "IXR-D2",
+				"pod1-leaf4": "IXR-D3L",
+			},
+			wantCounts: map[string]int{"IXR-D3L": 12, "IXR-D2": 4},
+		},
+		"ratio across pods": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyRatio, Ratio: []uint32{3, 1}},
+			want: map[string]string{
+				"pod1-leaf1": "IXR-D3L",
+				"pod1-leaf2": "IXR-D3L",
+				"pod2-leaf1": "IXR-D2",
+				"pod2-leaf2": "IXR-D3L",
+				"pod4-leaf1": "IXR-D2",
+			},
+			wantCounts: map[string]int{"IXR-D3L": 6, "IXR-D2": 2},
+		},
+		"ratio weights do not match the vendor infos": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyRatio, Ratio: []uint32{3, 1, 1}},
+			wantErr:    true,
+		},
+		"ratio without weight": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyRatio, Ratio: []uint32{0, 0}},
+			wantErr:    true,
+		},
+		"perIndex in turn": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyPerIndex},
+			want: map[string]string{
+				"pod1-leaf1": "IXR-D3L",
+				"pod1-leaf2": "IXR-D3L",
+				"pod2-leaf1": "IXR-D2",
+				"pod2-leaf2": "IXR-D2",
+				"pod3-leaf1": "IXR-D3L",
+			},
+		},
+		"perIndex indexes": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyPerIndex, Indexes: map[uint32]uint32{3: 1, 4: 1}},
+			want: map[string]string{
+				"pod1-leaf2": "IXR-D3L",
+				"pod2-leaf2": "IXR-D3L",
+				"pod3-leaf1": "IXR-D2",
+				"pod4-leaf2": "IXR-D2",
+			},
+		},
+		"perIndex unknown vendor info": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyPerIndex, Indexes: map[uint32]uint32{3: 2}},
+			wantErr:    true,
+		},
+		"redundancyGroup": {
+			leafs:      4,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyRedundancyGroup},
+			opts:       []Option{WithLeafGroups(&LeafGroupConfig{Size: 2})},
+			want: map[string]string{
+				"pod1-leaf1": "IXR-D3L",
+				"pod1-leaf2": "IXR-D2",
+				"pod2-leaf3": "IXR-D3L",
+				"pod2-leaf4": "IXR-D2",
+			},
+		},
+		"redundancyGroup without leaf groups": {
+			leafs:      4,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: VendorStrategyRedundancyGroup},
+			wantErr:    true,
+		},
+		"unknown strategy": {
+			leafs:      2,
+			assignment: &VendorAssignment{Position: "leaf", Strategy: "random"},
+			wantErr:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := tc.opts
+			if tc.assignment != nil {
+				opts = append(opts, WithVendorAssignments(tc.assignment))
+			}
+			f, err := newTestFabric(t, fmt.Sprintf(vendorTemplate, tc.leafs), opts...)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			for node, want := range tc.want {
+				if got := getTestNode(t, f, node).GetPlatform(); got != want {
+					t.Errorf("%s platform = %s, want %s", node, got, want)
+				}
+			}
+			if tc.wantCounts == nil {
+				return
+			}
+			counts := map[string]int{}
+			for _, n := range f.GetNodes() {
+				if n.GetPosition() == "leaf" {
+					counts[n.GetPlatform()]++
+				}
+			}
+			for platform, want := range tc.wantCounts {
+				if counts[platform] != want {
+					t.Errorf("%s leafs = %d, want %d", platform, counts[platform], want)
+				}
+			}
+		})
+	}
+}
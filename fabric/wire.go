@@ -0,0 +1,756 @@
+package fabric
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	topov1alpha1 "github.com/yndd/topology/apis/topo/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// TierPair identifies the two tiers a Wirer connects.
+type TierPair string
+
+const (
+	TierPairSpineLeaf       TierPair = "spine-leaf"
+	TierPairSuperspineSpine TierPair = "superspine-spine"
+	TierPairBorderLeafSpine TierPair = "borderleaf-spine"
+	// TierPairSpineSpine is only wired for collapsed spines, see WithCollapsedSpines
+	TierPairSpineSpine TierPair = "spine-spine"
+	// TierPairLeafLeaf is only wired for leaf groups, see WithLeafGroups
+	TierPairLeafLeaf TierPair = "leaf-leaf"
+	// TierPairLeafServer is only wired for servers, see WithServers
+	TierPairLeafServer TierPair = "leaf-server"
+	// TierPairBorderLeafSuperspine is only wired with BorderLeafAttachmentSuperspine
+	TierPairBorderLeafSuperspine TierPair = "borderleaf-superspine"
+)
+
+// wiringOrder is the order in which the tier pairs are wired
+var wiringOrder = []TierPair{
+	TierPairSpineLeaf,
+	TierPairLeafLeaf,
+	TierPairLeafServer,
+	TierPairSpineSpine,
+	TierPairSuperspineSpine,
+	TierPairBorderLeafSpine,
+	TierPairBorderLeafSuperspine,
+}
+
+// Wirer creates the links between the nodes of a tier pair.
+type Wirer interface {
+	Wire(w Wiring) error
+}
+
+// WirerFunc is a function that implements the Wirer interface.
+type WirerFunc func(w Wiring) error
+
+func (fn WirerFunc) Wire(w Wiring) error { return fn(w) }
+
+// Wiring gives a Wirer access to the nodes of the fabric and lets it add links.
+type Wiring interface {
+	// GetSettings returns the settings of the fabric template
+	GetSettings() *topov1alpha1.FabricTemplateSettings
+	// GetPodIndexes returns the global pod indexes in ascending order
+	GetPodIndexes() []uint32
+	// NodesByLabel returns the nodes matching the selector
+	NodesByLabel(selector labels.Selector) []Node
+	// AddLink adds a link from a downlink port of the upper node to an uplink port of the
+	// lower node, the interface indexes are allocated by the Wirer and must be unique per node
+	AddLink(from, to Node, fromIfIndex, toIfIndex uint32) Link
+	// GetMaxLeafsPerPod returns the number of leafs reserved per pod, the interface indexes
+	// above the leaf links of a spine are free for other links
+	GetMaxLeafsPerPod() uint32
+	// AddPeerLink adds a link between two nodes of the same tier on uplink port uplink of
+	// both nodes, the interface index is allocated by the Wirer and must be unique per node
+	AddPeerLink(a, b Node, uplink, ifIndex uint32) Link
+	// AddISL adds an inter-switch link between two nodes of the same tier, both interfaces
+	// are used without platform offset, so the Wirer allocates the interface range
+	AddISL(a, b Node, aIfIndex, bIfIndex uint32) Link
+}
+
+// WithWirer specifies the Wirer of a tier pair, replacing the default Wirer.
+// A nil Wirer disables the wiring of the tier pair.
+func WithWirer(tp TierPair, w Wirer) Option {
+	return func(f Fabric) {
+		f.SetWirer(tp, w)
+	}
+}
+
+// defaultWirers returns the wirers used if no Wirer is specified for a tier pair
+func defaultWirers() map[TierPair]Wirer {
+	return map[TierPair]Wirer{
+		TierPairSpineLeaf:       NewSpineLeafWirer(),
+		TierPairSuperspineSpine: NewPlaneAlignedWirer(),
+		TierPairBorderLeafSpine: NewBorderLeafSpineWirer(),
+	}
+}
+
+func (f *fabric) SetWirer(tp TierPair, w Wirer) { f.wirers[tp] = w }
+
+// wire runs the Wirer of every tier pair exactly once
+func (f *fabric) wire() error {
+	for _, tp := range wiringOrder {
+		w, ok := f.wirers[tp]
+		if !ok || w == nil {
+			continue
+		}
+		if err := w.Wire(f); err != nil {
+			return fmt.Errorf("%s wiring: %w", tp, err)
+		}
+	}
+	return nil
+}
+
+func (f *fabric) GetSettings() *topov1alpha1.FabricTemplateSettings { return f.settings }
+
+func (f *fabric) GetPodIndexes() []uint32 {
+	indexes := make([]uint32, 0, len(f.pods))
+	for _, pod := range f.pods {
+		indexes = append(indexes, pod.index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	return indexes
+}
+
+func (f *fabric) NodesByLabel(selector labels.Selector) []Node {
+	return f.nodesByLabel(selector)
+}
+
+func (f *fabric) AddLink(from, to Node, fromIfIndex, toIfIndex uint32) Link {
+	return f.addWiredLink(from, to,
+		fromIfIndex, to.GetUplinkPort(toIfIndex),
+		fromIfIndex, toIfIndex,
+		PortTypeDownlink, PortTypeUplink)
+}
+
+func (f *fabric) GetMaxLeafsPerPod() uint32 {
+	if f.ipam == nil {
+		return defaultMaxLeafsPerPod
+	}
+	return defaultUint32(f.ipam.MaxLeafsPerPod, defaultMaxLeafsPerPod)
+}
+
+func (f *fabric) AddPeerLink(a, b Node, uplink, ifIndex uint32) Link {
+	return f.addWiredLink(a, b,
+		a.GetUplinkPort(uplink), b.GetUplinkPort(uplink),
+		ifIndex, ifIndex,
+		PortTypeUplink, PortTypeUplink)
+}
+
+func (f *fabric) AddISL(a, b Node, aIfIndex, bIfIndex uint32) Link {
+	return f.addWiredLink(a, b,
+		aIfIndex, bIfIndex,
+		aIfIndex, bIfIndex,
+		"", "")
+}
+
+// addWiredLink adds a link between the ports of the nodes, the interface indexes
+// allocated by the Wirer are kept as endpoint labels next to the ports and the port
+// types, an empty port type is not validated against the port ranges of the platform
+func (f *fabric) addWiredLink(from, to Node, fromPort, toPort, fromIfIndex, toIfIndex uint32, fromType, toType string) Link {
+	l := f.addLink(from, to)
+
+	label := map[string]string{
+		from.String(): from.GetInterfaceName(fromPort),
+		to.String():   to.GetInterfaceName(toPort),
+		EndpointLabelKey(from.String(), KeyIfIndex): strconv.Itoa(int(fromIfIndex)),
+		EndpointLabelKey(to.String(), KeyIfIndex):   strconv.Itoa(int(toIfIndex)),
+		EndpointLabelKey(from.String(), KeyPort):    strconv.Itoa(int(fromPort)),
+		EndpointLabelKey(to.String(), KeyPort):      strconv.Itoa(int(toPort)),
+	}
+	if fromType != "" {
+		label[EndpointLabelKey(from.String(), KeyPortType)] = fromType
+	}
+	if toType != "" {
+		label[EndpointLabelKey(to.String(), KeyPortType)] = toType
+	}
+	l.SetLabel(label)
+
+	f.graph.SetLine(l)
+
+	f.log.Debug("Adding link", "from:", from.String(), "itfce", label[from.String()], "to:", to.String(), "itfce", label[to.String()])
+	return l
+}
+
+// positionSelector returns a selector for the nodes of a position, with optional extra labels
+func positionSelector(position topov1alpha1.Position, extra map[string]string) labels.Selector {
+	s := labels.Set{KeyPosition: string(position)}
+	for k, v := range extra {
+		s[k] = v
+	}
+	return labels.SelectorFromSet(s)
+}
+
+// NewSpineLeafWirer returns the default spine-leaf Wirer, which connects every spine
+// to every leaf within a pod.
+func NewSpineLeafWirer() Wirer {
+	return WirerFunc(wireSpineLeaf)
+}
+
+func wireSpineLeaf(w Wiring) error {
+	settings := w.GetSettings()
+	for _, podIndex := range w.GetPodIndexes() {
+		// identify all the leafs and spines in the podIndex
+		// from -> tier2 or spines
+		// to -> tier 3 or leafs
+		tier2Selector := labels.NewSelector()
+		tier3Selector := labels.NewSelector()
+
+		tier2Req, _ := labels.NewRequirement(KeyPosition, selection.Equals, []string{string(topov1alpha1.PositionSpine)})
+		tier3Req, _ := labels.NewRequirement(KeyPosition, selection.Equals, []string{string(topov1alpha1.PositionLeaf)})
+
+		// select the POD Index
+		podIdxReq, _ := labels.NewRequirement(KeyPodIndex, selection.Equals, []string{strconv.Itoa(int(podIndex))})
+
+		tier2Selector = tier2Selector.Add(*tier2Req, *podIdxReq)
+		tier3Selector = tier3Selector.Add(*tier3Req, *podIdxReq)
+
+		tier2Nodes := w.NodesByLabel(tier2Selector)
+		tier3Nodes := w.NodesByLabel(tier3Selector)
+
+		for _, tier2Node := range tier2Nodes {
+			for _, tier3Node := range tier3Nodes {
+				// validate if the uplinks per node is not greater than max uplinks
+				// otherwise there is a conflict and the algorithm behind will create
+				// overlapping indexes
+				uplinksPerNode := tier3Node.GetUplinkPerNode()
+				if uplinksPerNode > settings.MaxUplinksTier3ToTier2 {
+					return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier3ToTier2 %d",
+						uplinksPerNode, settings.MaxUplinksTier3ToTier2)
+				}
+
+				tier3NodeIndex, err := strconv.Atoi(tier3Node.GetRelativeNodeIndex())
+				if err != nil {
+					return err
+				}
+				tier2NodeIndex, err := strconv.Atoi(tier2Node.GetRelativeNodeIndex())
+				if err != nil {
+					return err
+				}
+
+				// the algorithm needs to avoid reindixing if changes happen -> introduced maxNumUplinks
+				// the allocation is first allocating the uplink Index
+				// u represnts the actual uplink index
+				// spine Index    -> actualUplinkId + (actual leafs  * max uplinks)
+				// leaf  Index    -> actualUplinkId + (actual spines * max uplinks)
+				// actualUplinkId = u + 1 -> counting starts at 1
+				// actual leafs   = tier3NodeIndex - 1 -> counting from 0
+				// actual spines  = tier2NodeIndex - 1 -> counting from 0
+				// max uplinks    = mergedTemplate.MaxUplinksTier3ToTier2
+				for u := uint32(0); u < uplinksPerNode; u++ {
+					w.AddLink(tier2Node, tier3Node,
+						u+1+((uint32(tier3NodeIndex)-1)*settings.MaxUplinksTier3ToTier2),
+						u+1+((uint32(tier2NodeIndex)-1)*settings.MaxUplinksTier3ToTier2),
+					)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// NewPlaneAlignedWirer returns the default superspine-spine Wirer, which connects
+// spine n of every pod to every superspine of plane n. A plane can have multiple
+// superspines to scale the tier1 layer horizontally.
+func NewPlaneAlignedWirer() Wirer {
+	return WirerFunc(wirePlaneAligned)
+}
+
+func wirePlaneAligned(w Wiring) error {
+	settings := w.GetSettings()
+	tier1Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSuperspine, nil))
+	tier2Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, nil))
+	if len(tier1Nodes) == 0 {
+		return nil
+	}
+
+	// superspines per plane index
+	planes := map[string][]Node{}
+	for _, tier1Node := range tier1Nodes {
+		planes[tier1Node.GetPlaneIndex()] = append(planes[tier1Node.GetPlaneIndex()], tier1Node)
+	}
+
+	// process superspine-spine links
+	for _, tier2Node := range tier2Nodes {
+		// validate if the uplinks per node is not greater than max uplinks
+		// otherwise there is a conflict and the algorithm behind will create
+		// overlapping indexes
+		uplinksPerNode := tier2Node.GetUplinkPerNode()
+		if uplinksPerNode > settings.MaxUplinksTier2ToTier1 {
+			return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, settings.MaxUplinksTier2ToTier1)
+		}
+
+		// spine and superspine line up so the spine connects to all superspines
+		// of the plane matching the spine index
+		planeNodes, ok := planes[tier2Node.GetRelativeNodeIndex()]
+		if !ok {
+			return fmt.Errorf("spine %s has no superspines in plane %s", tier2Node.String(), tier2Node.GetRelativeNodeIndex())
+		}
+
+		podIndex, err := strconv.Atoi(tier2Node.GetPodIndex())
+		if err != nil {
+			return err
+		}
+
+		for _, tier1Node := range planeNodes {
+			relativeIndex, err := strconv.Atoi(tier1Node.GetRelativeNodeIndex())
+			if err != nil {
+				return err
+			}
+
+			// the algorithm needs to avoid reindixing if changes happen -> introduced maxNumUplinks
+			// the allocation is first allocating the uplink Index
+			// u represnts the actual uplink index
+			// superspine Index -> actualUplinkId + (actual podIndex  * max uplinks)
+			// spine Index      -> actualUplinkId + (actual superspines in the plane * max uplinks)
+			// actualUplinkId              = u + 1 -> counting starts at 1
+			// actual PodIndex             = podIndex - 1
+			// actual superspines in plane = tier1Node.GetRelativeNodeIndex() - 1
+			// max uplinks                 = mergedTemplate.MaxUplinksTier2ToTier1
+			// a superspine connects to 1 spine per pod and a spine connects to 1 plane,
+			// so the indexes do not overlap when superspines are added to a plane
+			for u := uint32(0); u < uplinksPerNode; u++ {
+				w.AddLink(tier1Node, tier2Node,
+					u+1+(uint32(podIndex-1)*settings.MaxUplinksTier2ToTier1),
+					u+1+(uint32(relativeIndex-1)*settings.MaxUplinksTier2ToTier1),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// NewFullMeshWirer returns a superspine-spine Wirer, which connects every spine to
+// every superspine of every plane.
+// superspine Index -> actualUplinkId + ((podIndex - 1) * maxSpinesPerPod + spine index - 1) * max uplinks
+// spine Index      -> actualUplinkId + ((superspine index - 1) * maxSpinesPerPod + planeIndex - 1) * max uplinks
+func NewFullMeshWirer() Wirer {
+	return WirerFunc(wireFullMesh)
+}
+
+func wireFullMesh(w Wiring) error {
+	settings := w.GetSettings()
+	if settings.MaxSpinesPerPod == 0 {
+		return fmt.Errorf("maxSpinesPerPod must be set for full mesh wiring")
+	}
+	tier1Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSuperspine, nil))
+	tier2Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, nil))
+
+	for _, tier1Node := range tier1Nodes {
+		tier1Ordinal, err := tierOrdinal(tier1Node, settings.MaxSpinesPerPod)
+		if err != nil {
+			return err
+		}
+		for _, tier2Node := range tier2Nodes {
+			uplinksPerNode := tier2Node.GetUplinkPerNode()
+			if uplinksPerNode > settings.MaxUplinksTier2ToTier1 {
+				return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, settings.MaxUplinksTier2ToTier1)
+			}
+			tier2Ordinal, err := tierOrdinal(tier2Node, settings.MaxSpinesPerPod)
+			if err != nil {
+				return err
+			}
+			for u := uint32(0); u < uplinksPerNode; u++ {
+				w.AddLink(tier1Node, tier2Node,
+					u+1+uint32(tier2Ordinal)*settings.MaxUplinksTier2ToTier1,
+					u+1+uint32(tier1Ordinal)*settings.MaxUplinksTier2ToTier1,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// NewStripedWirer returns a superspine-spine Wirer, which stripes the spines of a pod
+// over the planes: spine n connects to the superspines of plane ((n - 1) % planes) + 1.
+// This allows less planes than spines per pod.
+// superspine Index -> actualUplinkId + ((podIndex - 1) * stripes + (spine index - 1) / planes) * max uplinks
+// spine Index      -> actualUplinkId + (superspine index - 1) * max uplinks
+// stripes          -> maxSpinesPerPod / planes, rounded up
+func NewStripedWirer() Wirer {
+	return WirerFunc(wireStriped)
+}
+
+func wireStriped(w Wiring) error {
+	settings := w.GetSettings()
+	if settings.MaxSpinesPerPod == 0 {
+		return fmt.Errorf("maxSpinesPerPod must be set for striped wiring")
+	}
+	tier1Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSuperspine, nil))
+	tier2Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, nil))
+
+	planes := map[string]struct{}{}
+	for _, tier1Node := range tier1Nodes {
+		planes[tier1Node.GetPlaneIndex()] = struct{}{}
+	}
+	if len(planes) == 0 {
+		return nil
+	}
+	numPlanes := uint32(len(planes))
+	stripes := (settings.MaxSpinesPerPod + numPlanes - 1) / numPlanes
+
+	for _, tier1Node := range tier1Nodes {
+		planeIndex, err := strconv.Atoi(tier1Node.GetPlaneIndex())
+		if err != nil {
+			return err
+		}
+		relativeIndex, err := strconv.Atoi(tier1Node.GetRelativeNodeIndex())
+		if err != nil {
+			return err
+		}
+		for _, tier2Node := range tier2Nodes {
+			uplinksPerNode := tier2Node.GetUplinkPerNode()
+			if uplinksPerNode > settings.MaxUplinksTier2ToTier1 {
+				return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, settings.MaxUplinksTier2ToTier1)
+			}
+			podIndex, err := strconv.Atoi(tier2Node.GetPodIndex())
+			if err != nil {
+				return err
+			}
+			tier2NodeIndex, err := strconv.Atoi(tier2Node.GetRelativeNodeIndex())
+			if err != nil {
+				return err
+			}
+			if uint32(tier2NodeIndex-1)%numPlanes+1 != uint32(planeIndex) {
+				continue
+			}
+			stripe := uint32(tier2NodeIndex-1) / numPlanes
+			for u := uint32(0); u < uplinksPerNode; u++ {
+				w.AddLink(tier1Node, tier2Node,
+					u+1+(uint32(podIndex-1)*stripes+stripe)*settings.MaxUplinksTier2ToTier1,
+					u+1+uint32(relativeIndex-1)*settings.MaxUplinksTier2ToTier1,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// NewBorderLeafSpineWirer returns the default borderleaf-spine Wirer, which connects
+// every borderleaf to every spine of every pod. The spines use their uplink ports for the
+// borderleafs after the uplinks to the superspines of their plane.
+// A borderleaf has no limit on the pods, the port validation reports the spines which
+// exceed the downlink ports of the borderleaf platform.
+// borderleaf Index -> actualUplinkId + ((podIndex - 1) * maxSpinesPerPod + spine index - 1) * max uplinks
+// spine Index      -> actualUplinkId + (superspines per plane + borderleaf index - 1) * max uplinks
+func NewBorderLeafSpineWirer() Wirer {
+	return WirerFunc(wireBorderLeafSpine)
+}
+
+func wireBorderLeafSpine(w Wiring) error {
+	settings := w.GetSettings()
+	blNodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionBorderLeaf, nil))
+	tier2Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, nil))
+	offset := superspinesPerPlane(w)
+
+	// process borderleaf-spine links
+	for _, blNode := range blNodes {
+		for _, tier2Node := range tier2Nodes {
+			// validate if the uplinks per node is not greater than max uplinks
+			// otherwise there is a conflict and the algorithm behind will create
+			// overlapping indexes
+			uplinksPerNode := borderLeafUplinks(blNode, tier2Node)
+			if uplinksPerNode > settings.MaxUplinksTier2ToTier1 {
+				return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, settings.MaxUplinksTier2ToTier1)
+			}
+
+			tier2Ordinal, err := tierOrdinal(tier2Node, settings.MaxSpinesPerPod)
+			if err != nil {
+				return err
+			}
+			blNodeIndex, err := strconv.Atoi(blNode.GetRelativeNodeIndex())
+			if err != nil {
+				return err
+			}
+
+			for u := uint32(0); u < uplinksPerNode; u++ {
+				w.AddLink(blNode, tier2Node,
+					u+1+uint32(tier2Ordinal)*settings.MaxUplinksTier2ToTier1,
+					u+1+((offset+uint32(blNodeIndex-1))*settings.MaxUplinksTier2ToTier1),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// borderLeafUplinks returns the number of links of the borderleaf to every upper node,
+// if the borderleaf tier has no uplinkPerNode the uplinks of the upper node are used,
+// or 1 without upper node
+func borderLeafUplinks(blNode, upper Node) uint32 {
+	if blNode.GetUplinkPerNode() != 0 {
+		return blNode.GetUplinkPerNode()
+	}
+	if upper != nil {
+		return upper.GetUplinkPerNode()
+	}
+	return 1
+}
+
+// superspinesPerPlane returns the highest relative index of the superspines, the spines
+// use that many blocks of max uplinks for the superspines of their plane
+func superspinesPerPlane(w Wiring) uint32 {
+	n := uint32(0)
+	for _, tier1Node := range w.NodesByLabel(positionSelector(topov1alpha1.PositionSuperspine, nil)) {
+		relativeIndex, err := strconv.Atoi(tier1Node.GetRelativeNodeIndex())
+		if err == nil {
+			n = maxUint32(n, uint32(relativeIndex))
+		}
+	}
+	return n
+}
+
+// BorderLeafAttachment specifies which tier the borderleafs are attached to.
+type BorderLeafAttachment string
+
+const (
+	// BorderLeafAttachmentSpine attaches the borderleafs to every spine of every pod
+	BorderLeafAttachmentSpine BorderLeafAttachment = "spine"
+	// BorderLeafAttachmentSuperspine attaches the borderleafs to every superspine
+	BorderLeafAttachmentSuperspine BorderLeafAttachment = "superspine"
+	// BorderLeafAttachmentBorderPod attaches the borderleafs to the spines of a dedicated border pod
+	BorderLeafAttachmentBorderPod BorderLeafAttachment = "borderPod"
+)
+
+const (
+	// AnnotationBorderLeafAttachment selects the BorderLeafAttachment in the template
+	AnnotationBorderLeafAttachment = "fabric.henderiw.io/borderleaf-attachment"
+	// AnnotationBorderPod selects the pod index of the border pod in the template
+	AnnotationBorderPod = "fabric.henderiw.io/border-pod"
+	// AnnotationCollapsedSpines selects collapsed spines in the template
+	AnnotationCollapsedSpines = "fabric.henderiw.io/collapsed-spines"
+)
+
+// templateOptions returns the options selected by the template annotations
+func templateOptions(t *topov1alpha1.Template) ([]Option, error) {
+	opts := []Option{}
+	if v, ok := t.GetAnnotations()[AnnotationCollapsedSpines]; ok {
+		collapsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s must be a boolean: %w", AnnotationCollapsedSpines, err)
+		}
+		if collapsed {
+			opts = append(opts, WithCollapsedSpines())
+		}
+	}
+
+	popts, err := podIndexOptions(t.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, popts...)
+	lopts, err := leafGroupOptions(t.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, lopts...)
+	bopts, err := breakoutOptions(t.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, bopts...)
+	vopts, err := vendorAssignmentOptions(t.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, vopts...)
+	iopts, err := ipamOptions(t.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, iopts...)
+	aopts, err := asnOptions(t.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, aopts...)
+	sopts, err := serverOptions(t.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, sopts...)
+
+	a, ok := t.GetAnnotations()[AnnotationBorderLeafAttachment]
+	if !ok {
+		return opts, nil
+	}
+	var borderPodIndex uint32
+	if BorderLeafAttachment(a) == BorderLeafAttachmentBorderPod {
+		idx, err := strconv.ParseUint(t.GetAnnotations()[AnnotationBorderPod], 10, 32)
+		if err != nil || idx == 0 {
+			return nil, fmt.Errorf("annotation %s must be a pod index with borderleaf attachment %s", AnnotationBorderPod, a)
+		}
+		borderPodIndex = uint32(idx)
+	}
+	return append(opts, WithBorderLeafAttachment(BorderLeafAttachment(a), borderPodIndex)), nil
+}
+
+// WithBorderLeafAttachment specifies which tier the borderleafs are attached to,
+// borderPodIndex is the pod index of the border pod for BorderLeafAttachmentBorderPod.
+func WithBorderLeafAttachment(a BorderLeafAttachment, borderPodIndex uint32) Option {
+	return func(f Fabric) {
+		f.SetWirer(TierPairBorderLeafSpine, nil)
+		f.SetWirer(TierPairBorderLeafSuperspine, nil)
+		switch a {
+		case BorderLeafAttachmentSpine:
+			f.SetWirer(TierPairBorderLeafSpine, NewBorderLeafSpineWirer())
+		case BorderLeafAttachmentSuperspine:
+			f.SetWirer(TierPairBorderLeafSuperspine, NewBorderLeafSuperspineWirer())
+		case BorderLeafAttachmentBorderPod:
+			f.SetWirer(TierPairBorderLeafSpine, NewBorderPodWirer(borderPodIndex))
+		default:
+			f.SetWirer(TierPairBorderLeafSpine, WirerFunc(func(w Wiring) error {
+				return fmt.Errorf("unknown borderleaf attachment %s", a)
+			}))
+		}
+	}
+}
+
+// NewBorderLeafSuperspineWirer returns a borderleaf-superspine Wirer, which connects
+// every borderleaf to every superspine. The superspines use their uplink ports for the
+// borderleafs, so the indexes are independent of the number of pods.
+// borderleaf Index -> actualUplinkId + ((superspine index - 1) * maxSpinesPerPod + planeIndex - 1) * max uplinks
+// superspine Index -> actualUplinkId + (borderleaf index - 1) * max uplinks
+func NewBorderLeafSuperspineWirer() Wirer {
+	return WirerFunc(wireBorderLeafSuperspine)
+}
+
+func wireBorderLeafSuperspine(w Wiring) error {
+	settings := w.GetSettings()
+	blNodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionBorderLeaf, nil))
+	tier1Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSuperspine, nil))
+	if len(blNodes) > 0 && len(tier1Nodes) == 0 {
+		return fmt.Errorf("borderleafs can not be attached to superspines without superspines")
+	}
+
+	for _, blNode := range blNodes {
+		uplinksPerNode := borderLeafUplinks(blNode, nil)
+		if uplinksPerNode > settings.MaxUplinksTier2ToTier1 {
+			return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, settings.MaxUplinksTier2ToTier1)
+		}
+		blNodeIndex, err := strconv.Atoi(blNode.GetRelativeNodeIndex())
+		if err != nil {
+			return err
+		}
+		for _, tier1Node := range tier1Nodes {
+			tier1Ordinal, err := tierOrdinal(tier1Node, settings.MaxSpinesPerPod)
+			if err != nil {
+				return err
+			}
+			for u := uint32(0); u < uplinksPerNode; u++ {
+				w.AddLink(blNode, tier1Node,
+					u+1+uint32(tier1Ordinal)*settings.MaxUplinksTier2ToTier1,
+					u+1+uint32(blNodeIndex-1)*settings.MaxUplinksTier2ToTier1,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// NewBorderPodWirer returns a borderleaf-spine Wirer, which connects every borderleaf
+// to the spines of the border pod only, so the borderleaf ports do not grow with the pods.
+// borderleaf Index -> actualUplinkId + (spine index - 1) * max uplinks
+// spine Index      -> actualUplinkId + (superspines per plane + borderleaf index - 1) * max uplinks
+func NewBorderPodWirer(podIndex uint32) Wirer {
+	return WirerFunc(func(w Wiring) error {
+		settings := w.GetSettings()
+		blNodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionBorderLeaf, nil))
+		tier2Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, map[string]string{
+			KeyPodIndex: strconv.Itoa(int(podIndex)),
+		}))
+		if len(blNodes) > 0 && len(tier2Nodes) == 0 {
+			return fmt.Errorf("border pod %d has no spines", podIndex)
+		}
+		offset := superspinesPerPlane(w)
+
+		for _, blNode := range blNodes {
+			blNodeIndex, err := strconv.Atoi(blNode.GetRelativeNodeIndex())
+			if err != nil {
+				return err
+			}
+			for _, tier2Node := range tier2Nodes {
+				uplinksPerNode := borderLeafUplinks(blNode, tier2Node)
+				if uplinksPerNode > settings.MaxUplinksTier2ToTier1 {
+					return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, settings.MaxUplinksTier2ToTier1)
+				}
+				tier2NodeIndex, err := strconv.Atoi(tier2Node.GetRelativeNodeIndex())
+				if err != nil {
+					return err
+				}
+				for u := uint32(0); u < uplinksPerNode; u++ {
+					w.AddLink(blNode, tier2Node,
+						u+1+uint32(tier2NodeIndex-1)*settings.MaxUplinksTier2ToTier1,
+						u+1+(offset+uint32(blNodeIndex-1))*settings.MaxUplinksTier2ToTier1,
+					)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// WithCollapsedSpines wires the spines of every pod as a collapsed spine pair, which
+// connects the two spines with each other instead of to superspines.
+func WithCollapsedSpines() Option {
+	return func(f Fabric) {
+		f.SetWirer(TierPairSpineSpine, NewCollapsedSpineWirer())
+	}
+}
+
+// NewCollapsedSpineWirer returns a spine-spine Wirer, which connects the two spines of
+// every pod with uplinkPerNode peer links. The peer links use the uplink ports of the
+// spines, so a fabric with collapsed spines can not have superspines. The interface
+// indexes of the peer links follow the leaf links, so they get their own link subnets.
+// spine port  -> actualUplinkId
+// spine Index -> maxLeafsPerPod * max uplinks tier3 + actualUplinkId
+func NewCollapsedSpineWirer() Wirer {
+	return WirerFunc(wireCollapsedSpines)
+}
+
+func wireCollapsedSpines(w Wiring) error {
+	settings := w.GetSettings()
+	if len(w.NodesByLabel(positionSelector(topov1alpha1.PositionSuperspine, nil))) > 0 {
+		return fmt.Errorf("collapsed spines can not be connected to superspines")
+	}
+
+	for _, podIndex := range w.GetPodIndexes() {
+		tier2Nodes := w.NodesByLabel(positionSelector(topov1alpha1.PositionSpine, map[string]string{
+			KeyPodIndex: strconv.Itoa(int(podIndex)),
+		}))
+		if len(tier2Nodes) != 2 {
+			return fmt.Errorf("pod %d has %d spines, collapsed spines must be a pair", podIndex, len(tier2Nodes))
+		}
+		// order the pair by relative index, so the links are always added the same way
+		sort.Slice(tier2Nodes, func(i, j int) bool {
+			return tier2Nodes[i].GetRelativeNodeIndex() < tier2Nodes[j].GetRelativeNodeIndex()
+		})
+
+		uplinksPerNode := tier2Nodes[0].GetUplinkPerNode()
+		if uplinksPerNode == 0 {
+			uplinksPerNode = 1
+		}
+		if uplinksPerNode > settings.MaxUplinksTier2ToTier1 {
+			return fmt.Errorf("uplink per node %d can not be bigger than maxUplinksTier2ToTier1 %d", uplinksPerNode, settings.MaxUplinksTier2ToTier1)
+		}
+		// the leaf links use the interface indexes up to maxLeafsPerPod * max uplinks
+		maxLeafs := w.GetMaxLeafsPerPod()
+		for _, leaf := range w.NodesByLabel(positionSelector(topov1alpha1.PositionLeaf, map[string]string{
+			KeyPodIndex: strconv.Itoa(int(podIndex)),
+		})) {
+			leafIndex, err := strconv.Atoi(leaf.GetRelativeNodeIndex())
+			if err != nil {
+				return err
+			}
+			if uint32(leafIndex) > maxLeafs {
+				return fmt.Errorf("leaf %s exceeds the %d leafs per pod of collapsed spines", leaf.String(), maxLeafs)
+			}
+		}
+		for u := uint32(0); u < uplinksPerNode; u++ {
+			w.AddPeerLink(tier2Nodes[0], tier2Nodes[1], u+1, maxLeafs*settings.MaxUplinksTier3ToTier2+u+1)
+		}
+	}
+	return nil
+}
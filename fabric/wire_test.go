@@ -0,0 +1,300 @@
+package fabric
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const borderLeafTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: borderleaf
+  annotations:
+    fabric.henderiw.io/borderleaf-attachment: %s
+    fabric.henderiw.io/border-pod: "2"
+spec:
+  properties:
+    fabric:
+      settings:
+        maxUplinksTier2ToTier1: 2
+        maxUplinksTier3ToTier2: 2
+        maxSpinesPerPod: 4
+      tier1:
+        num: 2
+        vendorInfo:
+        - vendorType: nokiaSRL
+          platform: IXR-D3
+      borderLeaf:
+        num: 2
+        uplinkPerNode: %d
+        vendorInfo:
+        - vendorType: nokiaSRL
+          platform: IXR-D3
+      pod:
+      - num: 2
+        tier2:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: 2
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+`
+
+// countLinks returns the number of links between the nodes
+func countLinks(f Fabric, a, b string) int {
+	n := 0
+	for _, l := range f.GetLinks() {
+		if (l.FromNodeName() == a && l.ToNodeName() == b) || (l.FromNodeName() == b && l.ToNodeName() == a) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBorderLeafAttachment(t *testing.T) {
+	ipam := &IPAMConfig{IPv4Prefix: "10.0.0.0/16", IPv6Prefix: "2001:db8::/48"}
+	for _, attachment := range []string{"spine", "superspine", "borderPod"} {
+		t.Run(attachment, func(t *testing.T) {
+			f := mustNewTestFabric(t, fmt.Sprintf(borderLeafTemplate, attachment, 1), WithIPAM(ipam))
+			if got := countLinks(f, "borderleaf1", "pod2-spine1") + countLinks(f, "borderleaf1", "plane1-superspine1"); got == 0 {
+				t.Errorf("borderleaf1 is not attached")
+			}
+			assertUniqueSubnets(t, f, KeyIPv4)
+		})
+	}
+}
+
+func TestBorderLeafUplinks(t *testing.T) {
+	tests := map[string]struct {
+		attachment    string
+		uplinkPerNode uint32
+		upper         string
+		want          int
+	}{
+		"spine": {
+			attachment:    "spine",
+			uplinkPerNode: 2,
+			upper:         "pod1-spine1",
+			want:          2,
+		},
+		"spine without uplinkPerNode": {
+			attachment:    "spine",
+			uplinkPerNode: 0,
+			upper:         "pod1-spine1",
+			want:          1,
+		},
+		"superspine": {
+			attachment:    "superspine",
+			uplinkPerNode: 2,
+			upper:         "plane1-superspine1",
+			want:          2,
+		},
+		"superspine without uplinkPerNode": {
+			attachment:    "superspine",
+			uplinkPerNode: 0,
+			upper:         "plane1-superspine1",
+			want:          1,
+		},
+		"border pod": {
+			attachment:    "borderPod",
+			uplinkPerNode: 2,
+			upper:         "pod2-spine1",
+			want:          2,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f := mustNewTestFabric(t, fmt.Sprintf(borderLeafTemplate, tc.attachment, tc.uplinkPerNode))
+			if got := getTestNode(t, f, "borderleaf1").GetUplinkPerNode(); got != tc.uplinkPerNode {
+				t.Errorf("borderleaf1 uplinkPerNode = %d, want %d", got, tc.uplinkPerNode)
+			}
+			if got := countLinks(f, "borderleaf1", tc.upper); got != tc.want {
+				t.Errorf("links between borderleaf1 and %s = %d, want %d", tc.upper, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestBorderLeafSpinePods verifies a borderleaf attached to spines takes any number of pods,
+// the pods beyond its downlink ports are reported by the port validation
+func TestBorderLeafSpinePods(t *testing.T) {
+	tests := map[string]struct {
+		pods          int
+		link          string
+		wantPortError string
+	}{
+		"more pods than spines per pod": {
+			pods: 3,
+			link: "borderleaf1:int-1/11--pod3-spine2:int-1/29",
+		},
+		"exceeds the borderleaf downlinks": {
+			pods:          7,
+			wantPortError: "borderleaf1",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			template := fmt.Sprintf(borderLeafTemplate, "spine", 1)
+			template = strings.Replace(template, "maxSpinesPerPod: 4", "maxSpinesPerPod: 2", 1)
+			template = strings.Replace(template, "- num: 2", fmt.Sprintf("- num: %d", tc.pods), 1)
+			f, err := newTestFabric(t, template)
+			if tc.wantPortError != "" {
+				perr := &PortCapacityError{}
+				if !errors.As(err, &perr) {
+					t.Fatalf("New() error = %v, want a PortCapacityError", err)
+				}
+				for _, pe := range perr.Errors {
+					if pe.Node == tc.wantPortError {
+						return
+					}
+				}
+				t.Fatalf("port errors = %s, want an error of %s", perr, tc.wantPortError)
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for _, l := range f.GetLinks() {
+				if linkKey(l) == tc.link {
+					return
+				}
+			}
+			t.Errorf("link %s not found", tc.link)
+		})
+	}
+}
+
+const superspineTemplate = `
+apiVersion: topo.yndd.io/v1alpha1
+kind: Template
+metadata:
+  name: superspine
+spec:
+  properties:
+    fabric:
+      settings:
+        maxUplinksTier2ToTier1: 1
+        maxUplinksTier3ToTier2: 1
+        maxSpinesPerPod: 2
+      tier1:
+        num: 2
+        vendorInfo:
+        - vendorType: nokiaSRL
+          platform: IXR-D3
+      pod:
+      - num: 2
+        tier2:
+          num: 2
+          uplinkPerNode: %d
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+        tier3:
+          num: 1
+          uplinkPerNode: 1
+          vendorInfo:
+          - vendorType: nokiaSRL
+            platform: IXR-D3
+`
+
+func TestSuperspineWirers(t *testing.T) {
+	// 2 pods of 2 spines and 2 planes of 2 superspines
+	tests := map[string]struct {
+		wirer         Wirer
+		uplinkPerNode int
+		// want is the number of superspine-spine links
+		want int
+		// wantLinks are links which must exist
+		wantLinks []string
+		// wantNoLinks are the node pairs which must not be connected
+		wantNoLinks [][2]string
+		wantErr     bool
+	}{
+		"plane aligned": {
+			wirer:         NewPlaneAlignedWirer(),
+			uplinkPerNode: 1,
+			want:          4 * 2,
+			wantLinks: []string{
+				"plane1-superspine1:int-1/1--pod1-spine1:int-1/25",
+				"plane1-superspine2:int-1/1--pod1-spine1:int-1/26",
+				"plane2-superspine2:int-1/2--pod2-spine2:int-1/26",
+			},
+			wantNoLinks: [][2]string{{"plane1-superspine1", "pod1-spine2"}, {"plane2-superspine1", "pod2-spine1"}},
+		},
+		"plane aligned uplinks exceed max uplinks": {
+			wirer:         NewPlaneAlignedWirer(),
+			uplinkPerNode: 2,
+			wantErr:       true,
+		},
+		"full mesh": {
+			wirer:         NewFullMeshWirer(),
+			uplinkPerNode: 1,
+			want:          4 * 4,
+			wantLinks: []string{
+				"plane1-superspine1:int-1/1--pod1-spine1:int-1/25",
+				"plane2-superspine2:int-1/4--pod2-spine2:int-1/28",
+				"plane1-superspine1:int-1/2--pod1-spine2:int-1/25",
+			},
+		},
+		"full mesh uplinks exceed max uplinks": {
+			wirer:         NewFullMeshWirer(),
+			uplinkPerNode: 2,
+			wantErr:       true,
+		},
+		"striped": {
+			wirer:         NewStripedWirer(),
+			uplinkPerNode: 1,
+			want:          4 * 2,
+			wantLinks: []string{
+				"plane1-superspine1:int-1/1--pod1-spine1:int-1/25",
+				"plane2-superspine2:int-1/2--pod2-spine2:int-1/26",
+			},
+			wantNoLinks: [][2]string{{"plane1-superspine1", "pod1-spine2"}},
+		},
+		"striped uplinks exceed max uplinks": {
+			wirer:         NewStripedWirer(),
+			uplinkPerNode: 2,
+			wantErr:       true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			f, err := newTestFabric(t, fmt.Sprintf(superspineTemplate, tc.uplinkPerNode), WithWirer(TierPairSuperspineSpine, tc.wirer))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New() error = %v, wantErr %t", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			links := map[string]bool{}
+			got := 0
+			for _, l := range f.GetLinks() {
+				links[linkKey(l)] = true
+				if l.From().(Node).GetPosition() == "superspine" || l.To().(Node).GetPosition() == "superspine" {
+					got++
+				}
+			}
+			if got != tc.want {
+				t.Errorf("superspine-spine links = %d, want %d", got, tc.want)
+			}
+			for _, want := range tc.wantLinks {
+				if !links[want] {
+					t.Errorf("link %s not found", want)
+				}
+			}
+			for _, pair := range tc.wantNoLinks {
+				if n := countLinks(f, pair[0], pair[1]); n != 0 {
+					t.Errorf("%s and %s have %d links, want none", pair[0], pair[1], n)
+				}
+			}
+		})
+	}
+}
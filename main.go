@@ -1,9 +1,12 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/henderiw/fabric/fabric"
 	"github.com/yndd/ndd-runtime/pkg/logging"
@@ -11,39 +14,442 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
+const usage = `usage: fabric <command> [flags]
+
+commands:
+  build     build the fabric and write the topology json to the output directory
+  graph     write the fabric graph in DOT format
+  export    export the fabric as clab, json, dot or csv
+  validate  validate the template
+  paths     report the equal-cost shortest paths between two nodes or every pair of leafs
+  failure   report the impact of failed nodes and links on the leafs
+
+run 'fabric <command> -h' for the flags of a command
+`
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const (
+	formatClab = "clab"
+	formatJSON = "json"
+	formatDOT  = "dot"
+	formatCSV  = "csv"
+)
+
+// stdio is the name used for stdin/stdout in the file flags
+const stdio = "-"
+
 func main() {
-	zlog := zap.New(zap.UseDevMode(true), zap.JSONEncoder())
-	logger := logging.NewLogrLogger(zlog.WithName("fabric"))
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
 
-	d, err := os.ReadFile("./example/template.json")
-	if err != nil {
-		panic(err)
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
 	}
-	//fmt.Printf("raw ytes: \n%s\n", string(d))
 
-	t := topov1alpha1.Template{}
-	if err := json.Unmarshal(d, &t); err != nil {
-		panic(err)
+	var err error
+	switch args[0] {
+	case "build":
+		err = runBuild(args[1:], stdin, stderr)
+	case "graph":
+		err = runGraph(args[1:], stdin, stdout, stderr)
+	case "export":
+		err = runExport(args[1:], stdin, stdout, stderr)
+	case "validate":
+		err = runValidate(args[1:], stdin, stdout, stderr)
+	case "paths":
+		err = runPaths(args[1:], stdin, stdout, stderr)
+	case "failure":
+		err = runFailure(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
 	}
 
-	f, err := fabric.New(&t,
-		fabric.WithLogger(logger),
-		fabric.WithLocation(&topov1alpha1.Location{
-			Latitude:  "51.090875423265956",
-			Longitude: "4.87314214079595",
-		}),
-	)
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		return exitUsage
+	default:
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return exitError
+	}
+}
+
+// errUsage is returned when the flags of a command are not valid
+var errUsage = errors.New("usage error")
+
+// options are the flags shared by all commands
+type options struct {
+	template     string
+	templateName string
+	templateDir  string
+	catalog      string
+	namespace    string
+	latitude     string
+	longitude    string
+	debug        bool
+}
+
+func newFlagSet(name string, stderr io.Writer) (*flag.FlagSet, *options) {
+	o := &options{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&o.template, "template", "", "path of the JSON or YAML template or rail template file, - reads from stdin")
+	fs.StringVar(&o.templateName, "template-name", "", "name of the (rail) template resource, required if the file has multiple templates")
+	fs.StringVar(&o.templateDir, "template-dir", "", "directory with the JSON or YAML files of the referenced templates and definitions")
+	fs.StringVar(&o.catalog, "platform-catalog", "", "path of a JSON or YAML platform catalog, added to the embedded catalog")
+	fs.StringVar(&o.namespace, "namespace", "", "namespace of the template, overrides the template namespace")
+	fs.StringVar(&o.latitude, "latitude", "", "latitude of the fabric location")
+	fs.StringVar(&o.longitude, "longitude", "", "longitude of the fabric location")
+	fs.BoolVar(&o.debug, "debug", false, "enable debug logging")
+	return fs, o
+}
+
+func parseFlags(fs *flag.FlagSet, o *options, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments %v\n", fs.Args())
+		return errUsage
+	}
+	if o.template == "" {
+		fmt.Fprintln(fs.Output(), "flag -template is required")
+		return errUsage
+	}
+	return nil
+}
+
+func runBuild(args []string, stdin io.Reader, stderr io.Writer) error {
+	fs, o := newFlagSet("build", stderr)
+	outputDir := fs.String("output-dir", fabric.DefaultOutputDir, "directory the topology json is written to")
+	if err := parseFlags(fs, o, args); err != nil {
+		return err
+	}
+
+	f, err := newFabric(o, stdin, stderr, fabric.WithOutputDir(*outputDir))
 	if err != nil {
-		panic(err)
+		return err
 	}
 	f.PrintNodes()
 	f.PrintLinks()
+	return f.GenerateJsonFile()
+}
+
+func runGraph(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs, o := newFlagSet("graph", stderr)
+	output := fs.String("output", stdio, "output file, - writes to stdout")
+	if err := parseFlags(fs, o, args); err != nil {
+		return err
+	}
+
+	f, err := newFabric(o, stdin, stderr)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, stdout, f.WriteGraph)
+}
+
+func runExport(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs, o := newFlagSet("export", stderr)
+	format := fs.String("format", formatJSON, "export format: clab, json, dot or csv")
+	output := fs.String("output", stdio, "output file, - writes to stdout")
+	name := fs.String("name", "fabric", "name of the containerlab topology")
+	if err := parseFlags(fs, o, args); err != nil {
+		return err
+	}
+
+	switch *format {
+	case formatClab, formatJSON, formatDOT, formatCSV:
+	default:
+		fmt.Fprintf(stderr, "unknown format %q\n", *format)
+		return errUsage
+	}
+
+	f, err := newFabric(o, stdin, stderr)
+	if err != nil {
+		return err
+	}
+
+	var write func(w io.Writer) error
+	switch *format {
+	case formatClab:
+		write = func(w io.Writer) error { return f.WriteContainerlab(w, *name) }
+	case formatJSON:
+		write = f.WriteTopologyJSON
+	case formatDOT:
+		write = f.WriteGraph
+	case formatCSV:
+		write = f.WriteLinksCSV
+	}
+	return writeOutput(*output, stdout, write)
+}
+
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs, o := newFlagSet("validate", stderr)
+	ports := fs.Bool("ports", false, "report the free ports of every node")
+	if err := parseFlags(fs, o, args); err != nil {
+		return err
+	}
+
+	f, err := newFabric(o, stdin, stderr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "template %s is valid: %d nodes, %d links\n", o.template, len(f.GetNodes()), len(f.GetLinks()))
+	if *ports {
+		for _, np := range f.GetPortReport() {
+			if np.Ports == 0 {
+				fmt.Fprintf(stdout, "%s (%s, %s): %d ports used, platform not in catalog\n", np.Node, np.Tier, np.Platform, len(np.Used))
+				continue
+			}
+			fmt.Fprintf(stdout, "%s (%s, %s): %d/%d ports used, free %s\n", np.Node, np.Tier, np.Platform, len(np.Used), np.Ports, formatPorts(np.Free))
+		}
+	}
+	return nil
+}
+
+func runPaths(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs, o := newFlagSet("paths", stderr)
+	from := fs.String("from", "", "name of the source node, without -from and -to the paths of every pair of leafs are reported")
+	to := fs.String("to", "", "name of the destination node")
+	links := fs.Bool("links", false, "print the link sequences of every path")
+	if err := parseFlags(fs, o, args); err != nil {
+		return err
+	}
+	if (*from == "") != (*to == "") {
+		fmt.Fprintln(stderr, "flags -from and -to must be used together")
+		return errUsage
+	}
+
+	f, err := newFabric(o, stdin, stderr)
+	if err != nil {
+		return err
+	}
+
+	if *from == "" {
+		for _, r := range f.GetLeafPaths() {
+			fmt.Fprintf(stdout, "%s -> %s: %d paths, %d hops\n", r.From, r.To, r.Paths, r.Hops)
+		}
+		return nil
+	}
+	r, err := f.GetPaths(*from, *to)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s -> %s: %d paths, %d hops\n", r.From, r.To, r.Paths, r.Hops)
+	for _, np := range r.NodePaths {
+		fmt.Fprintf(stdout, "  %s (%d paths)\n", strings.Join(np.Nodes, " -> "), np.Paths)
+		if !*links {
+			continue
+		}
+		for _, seq := range np.LinkSequences() {
+			fmt.Fprintf(stdout, "    %s\n", strings.Join(seq, " "))
+		}
+	}
+	return nil
+}
+
+func runFailure(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs, o := newFlagSet("failure", stderr)
+	nodes := fs.String("nodes", "", "comma separated names of the failed nodes")
+	nodeSelector := fs.String("node-selector", "", "label selector of the failed nodes, e.g. position=superspine,planeIndex=1")
+	links := fs.String("links", "", "comma separated names of the failed links")
+	linkSelector := fs.String("link-selector", "", "label selector of the failed links")
+	if err := parseFlags(fs, o, args); err != nil {
+		return err
+	}
+	failure := &fabric.Failure{
+		Nodes:        splitList(*nodes),
+		NodeSelector: *nodeSelector,
+		Links:        splitList(*links),
+		LinkSelector: *linkSelector,
+	}
+	if len(failure.Nodes) == 0 && failure.NodeSelector == "" && len(failure.Links) == 0 && failure.LinkSelector == "" {
+		fmt.Fprintln(stderr, "one of the flags -nodes, -node-selector, -links or -link-selector is required")
+		return errUsage
+	}
+
+	f, err := newFabric(o, stdin, stderr)
+	if err != nil {
+		return err
+	}
+	r, err := f.SimulateFailure(failure)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "failed nodes: %d, failed links: %d\n", len(r.FailedNodes), len(r.FailedLinks))
+	for _, n := range r.FailedNodes {
+		fmt.Fprintf(stdout, "  %s\n", n)
+	}
+	fmt.Fprintf(stdout, "leaf pairs: %d, lost paths: %d, disconnected: %d\n", len(r.LeafPairs), r.LostPaths, r.Disconnected)
+	for _, p := range r.LeafPairs {
+		if !p.Changed() {
+			continue
+		}
+		fmt.Fprintf(stdout, "  %s -> %s: %d/%d paths, %d/%d hops\n", p.From, p.To, p.Paths, p.PathsBefore, p.Hops, p.HopsBefore)
+	}
+	fmt.Fprintln(stdout, "uplinks:")
+	for _, u := range r.Uplinks {
+		fmt.Fprintf(stdout, "  %s: %d/%d links, %gG/%gG\n", u.Leaf, u.Links, u.LinksBefore, u.BandwidthGbps, u.BandwidthBefore)
+	}
+	fmt.Fprintf(stdout, "partitions: %d\n", len(r.Partitions))
+	if len(r.Partitions) > 1 {
+		for _, p := range r.Partitions {
+			fmt.Fprintf(stdout, "  %s\n", strings.Join(p, ","))
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, an empty string is an empty list
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// formatPorts formats the ports as ranges, e.g. 1-4,7
+func formatPorts(ports []uint32) string {
+	if len(ports) == 0 {
+		return "none"
+	}
+	ranges := []string{}
+	for i := 0; i < len(ports); {
+		j := i
+		for j+1 < len(ports) && ports[j+1] == ports[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("%d", ports[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", ports[i], ports[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// newFabric builds the fabric from the template and flags of the command
+func newFabric(o *options, stdin io.Reader, stderr io.Writer, opts ...fabric.Option) (fabric.Fabric, error) {
+	zlog := zap.New(zap.UseDevMode(o.debug), zap.JSONEncoder(), zap.WriteTo(stderr))
+	logger := logging.NewLogrLogger(zlog.WithName("fabric"))
+
+	res, err := readResources(o.template, stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	opts = append([]fabric.Option{fabric.WithLogger(logger)}, opts...)
+	if o.catalog != "" {
+		c, err := readPlatformCatalog(o.catalog)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, fabric.WithPlatformCatalog(c))
+	}
+	if o.latitude != "" || o.longitude != "" {
+		opts = append(opts, fabric.WithLocation(&topov1alpha1.Location{
+			Latitude:  o.latitude,
+			Longitude: o.longitude,
+		}))
+	}
 
-	fmt.Println("@@@@@@@@@@@@@@@@@@@@@@@@@@")
-	fmt.Println()
-	f.PrintGraph()
+	// a file with only rail templates builds a rail-optimized fabric
+	if len(res.Templates) == 0 && len(res.RailTemplates) > 0 {
+		rt, err := fabric.SelectRailTemplate(res.RailTemplates, o.templateName)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read template %s: %w", o.template, err)
+		}
+		return fabric.NewRail(rt, opts...)
+	}
 
-	if err := f.GenerateJsonFile(); err != nil {
-		panic(err)
+	t, err := fabric.SelectTemplate(res.Templates, o.templateName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read template %s: %w", o.template, err)
+	}
+	if o.namespace != "" {
+		t.Namespace = o.namespace
+	}
+
+	// template and definition references are resolved from the template directory,
+	// or else from the other resources in the template file
+	resolver := fabric.NewResourcesResolver(res)
+	if o.templateDir != "" {
+		resolver = fabric.NewDirResolver(o.templateDir)
+	}
+
+	return fabric.New(t, append(opts, fabric.WithTemplateResolver(resolver))...)
+}
+
+// readResources reads the template and definition resources from the JSON or YAML file,
+// - reads from stdin
+func readResources(path string, stdin io.Reader) (*fabric.Resources, error) {
+	r := stdin
+	if path != stdio {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	res, err := fabric.ReadResources(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read template %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// readPlatformCatalog reads the platform catalog file and adds it to the embedded catalog
+func readPlatformCatalog(path string) (*fabric.PlatformCatalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	pc, err := fabric.LoadPlatformCatalog(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read platform catalog %s: %w", path, err)
+	}
+	c := fabric.DefaultPlatformCatalog()
+	c.Add(pc.Platforms...)
+	return c, nil
+}
+
+// writeOutput calls write with the output file, - writes to stdout
+func writeOutput(path string, stdout io.Writer, write func(w io.Writer) error) (err error) {
+	if path == stdio {
+		return write(stdout)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+	return write(file)
 }
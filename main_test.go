@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/henderiw/fabric/fabric"
+	"sigs.k8s.io/yaml"
+)
+
+const exampleTemplate = "example/template.yaml"
+
+// runTest runs the command with the stdin and returns the exit code, stdout and stderr
+func runTest(t *testing.T, args []string, stdin string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRunExitCodes(t *testing.T) {
+	example, err := os.ReadFile(exampleTemplate)
+	if err != nil {
+		t.Fatalf("cannot read example template: %s", err)
+	}
+	tests := map[string]struct {
+		args       []string
+		stdin      string
+		want       int
+		wantStdout string
+		wantStderr string
+	}{
+		"no command": {
+			want:       exitUsage,
+			wantStderr: "usage: fabric",
+		},
+		"help": {
+			args:       []string{"help"},
+			want:       exitOK,
+			wantStdout: "usage: fabric",
+		},
+		"unknown command": {
+			args:       []string{"deploy"},
+			want:       exitUsage,
+			wantStderr: `unknown command "deploy"`,
+		},
+		"command help": {
+			args: []string{"validate", "-h"},
+			want: exitOK,
+		},
+		"unknown flag": {
+			args: []string{"validate", "-template", exampleTemplate, "-pods"},
+			want: exitUsage,
+		},
+		"unexpected argument": {
+			args:       []string{"validate", "-template", exampleTemplate, "pods"},
+			want:       exitUsage,
+			wantStderr: "unexpected arguments",
+		},
+		"without template": {
+			args:       []string{"validate"},
+			want:       exitUsage,
+			wantStderr: "flag -template is required",
+		},
+		"validate": {
+			args:       []string{"validate", "-template", exampleTemplate},
+			want:       exitOK,
+			wantStdout: "is valid: 16 nodes, 48 links",
+		},
+		"validate stdin": {
+			args:       []string{"validate", "-template", "-"},
+			stdin:      string(example),
+			want:       exitOK,
+			wantStdout: "template - is valid",
+		},
+		"validate ports": {
+			args:       []string{"validate", "-template", exampleTemplate, "-ports"},
+			want:       exitOK,
+			wantStdout: "pod1-leaf1 (leaf, IXR-D3L): 4/32 ports used, free 1-26,31-32",
+		},
+		"missing template": {
+			args:       []string{"validate", "-template", "missing.yaml"},
+			want:       exitError,
+			wantStderr: "error: ",
+		},
+		"invalid template": {
+			args:       []string{"validate", "-template", "-"},
+			stdin:      "kind: Template\nspec: [",
+			want:       exitError,
+			wantStderr: "cannot read template -",
+		},
+		"unknown template name": {
+			args:       []string{"validate", "-template", exampleTemplate, "-template-name", "other"},
+			want:       exitError,
+			wantStderr: "other",
+		},
+		"unknown export format": {
+			args:       []string{"export", "-template", exampleTemplate, "-format", "xml"},
+			want:       exitUsage,
+			wantStderr: `unknown format "xml"`,
+		},
+		"graph": {
+			args:       []string{"graph", "-template", exampleTemplate},
+			want:       exitOK,
+			wantStdout: "strict graph {",
+		},
+		"paths": {
+			args:       []string{"paths", "-template", exampleTemplate, "-from", "pod1-leaf1", "-to", "pod1-leaf2"},
+			want:       exitOK,
+			wantStdout: "pod1-leaf1 -> pod1-leaf2: 8 paths, 2 hops",
+		},
+		"leaf paths": {
+			args:       []string{"paths", "-template", exampleTemplate},
+			want:       exitOK,
+			wantStdout: "pod1-leaf1 -> pod2-leaf4: 64 paths, 4 hops",
+		},
+		"paths without to": {
+			args:       []string{"paths", "-template", exampleTemplate, "-from", "pod1-leaf1"},
+			want:       exitUsage,
+			wantStderr: "flags -from and -to must be used together",
+		},
+		"paths of an unknown node": {
+			args:       []string{"paths", "-template", exampleTemplate, "-from", "pod1-leaf1", "-to", "pod3-leaf1"},
+			want:       exitError,
+			wantStderr: "pod3-leaf1",
+		},
+		"failure": {
+			args:       []string{"failure", "-template", exampleTemplate, "-node-selector", "position=superspine"},
+			want:       exitOK,
+			wantStdout: "partitions: 2",
+		},
+		"failure without failed nodes or links": {
+			args:       []string{"failure", "-template", exampleTemplate},
+			want:       exitUsage,
+			wantStderr: "is required",
+		},
+		"failure of an unknown node": {
+			args:       []string{"failure", "-template", exampleTemplate, "-nodes", "pod3-spine1"},
+			want:       exitError,
+			wantStderr: "unknown node pod3-spine1",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			code, stdout, stderr := runTest(t, tc.args, tc.stdin)
+			if code != tc.want {
+				t.Errorf("run() = %d, want %d, stderr: %s", code, tc.want, stderr)
+			}
+			if !strings.Contains(stdout, tc.wantStdout) {
+				t.Errorf("stdout = %q, want %q", stdout, tc.wantStdout)
+			}
+			if !strings.Contains(stderr, tc.wantStderr) {
+				t.Errorf("stderr = %q, want %q", stderr, tc.wantStderr)
+			}
+		})
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	tests := map[string]struct {
+		args []string
+		// check validates the output
+		check func(t *testing.T, out []byte)
+	}{
+		"clab": {
+			args: []string{"-format", "clab", "-name", "dc1"},
+			check: func(t *testing.T, out []byte) {
+				topo := &fabric.ClabTopologyFile{}
+				if err := yaml.Unmarshal(out, topo); err != nil {
+					t.Fatalf("cannot read the containerlab topology: %s", err)
+				}
+				if topo.Name != "dc1" || len(topo.Topology.Nodes) != 16 || len(topo.Topology.Links) != 48 {
+					t.Errorf("topology %s has %d nodes and %d links, want dc1 with 16 nodes and 48 links",
+						topo.Name, len(topo.Topology.Nodes), len(topo.Topology.Links))
+				}
+			},
+		},
+		"json": {
+			args: []string{"-format", "json"},
+			check: func(t *testing.T, out []byte) {
+				topo := map[string][]json.RawMessage{}
+				if err := json.Unmarshal(out, &topo); err != nil {
+					t.Fatalf("cannot read the topology json: %s", err)
+				}
+				if len(topo["nodes"]) != 16 || len(topo["edges"]) != 48 {
+					t.Errorf("topology has %d nodes and %d edges, want 16 and 48", len(topo["nodes"]), len(topo["edges"]))
+				}
+			},
+		},
+		"dot": {
+			args: []string{"-format", "dot"},
+			check: func(t *testing.T, out []byte) {
+				edge := bytes.Contains(out, []byte(`"pod1-leaf1" -- "pod1-spine1"`)) ||
+					bytes.Contains(out, []byte(`"pod1-spine1" -- "pod1-leaf1"`))
+				if !bytes.HasPrefix(out, []byte("strict graph {")) || !edge {
+					t.Errorf("output is not the dot graph of the fabric:\n%s", out)
+				}
+			},
+		},
+		"csv": {
+			args: []string{"-format", "csv"},
+			check: func(t *testing.T, out []byte) {
+				records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+				if err != nil {
+					t.Fatalf("cannot read the csv: %s", err)
+				}
+				if len(records) != 49 || strings.Join(records[0], ",") != "fromNode,fromIfName,toNode,toIfName" {
+					t.Errorf("csv has %d records with header %v, want 49 with header fromNode,fromIfName,toNode,toIfName",
+						len(records), records[0])
+				}
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := append([]string{"export", "-template", exampleTemplate}, tc.args...)
+			code, stdout, stderr := runTest(t, args, "")
+			if code != exitOK {
+				t.Fatalf("run() = %d, want %d, stderr: %s", code, exitOK, stderr)
+			}
+			tc.check(t, []byte(stdout))
+
+			// the same output is written to the output file
+			output := filepath.Join(t.TempDir(), "fabric."+name)
+			if code, _, stderr := runTest(t, append(args, "-output", output), ""); code != exitOK {
+				t.Fatalf("run() = %d, want %d, stderr: %s", code, exitOK, stderr)
+			}
+			b, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatalf("cannot read output: %s", err)
+			}
+			tc.check(t, b)
+		})
+	}
+}
+
+func TestRunBuild(t *testing.T) {
+	dir := t.TempDir()
+	code, _, stderr := runTest(t, []string{"build", "-template", exampleTemplate, "-output-dir", dir}, "")
+	if code != exitOK {
+		t.Fatalf("run() = %d, want %d, stderr: %s", code, exitOK, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fabric.json")); err != nil {
+		t.Errorf("topology json not written: %s", err)
+	}
+}